@@ -0,0 +1,302 @@
+package context
+
+import (
+	"net/http"
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Backend associates stores with requests. Implementations must be safe for
+// concurrent use.
+//
+// The default backend keys stores by request pointer in a map guarded by a
+// single lock. Other strategies can be selected with SetBackend.
+type Backend interface {
+	// Attach returns the store of r, creating one with NewStore if r has
+	// none. created reports whether the store was created.
+	Attach(r *http.Request) (s *Store, created bool)
+	// Lookup returns the store of r, or nil if r has none.
+	Lookup(r *http.Request) *Store
+	// Release removes the store of r and returns it, or nil if r has none.
+	Release(r *http.Request) *Store
+	// Range calls f for every request with a store, until f returns false.
+	Range(f func(r *http.Request, s *Store) bool)
+}
+
+var backend atomic.Value
+
+func init() {
+	backend.Store(backendBox{NewMapBackend()})
+}
+
+// backendBox gives every backend the same concrete type in atomic.Value.
+type backendBox struct {
+	Backend
+}
+
+// SetBackend replaces the backend used to associate stores with requests.
+// It is meant to be called during program initialization: values stored
+// with the previous backend are not moved and become unreachable.
+func SetBackend(b Backend) {
+	rb, _ := b.(*RecordingBackend)
+	recorder.Store(rb)
+	backend.Store(backendBox{b})
+}
+
+// CurrentBackend returns the backend set with SetBackend, so that code
+// replacing it temporarily, such as benchmarks, can restore it.
+func CurrentBackend() Backend {
+	return currentBackend()
+}
+
+func currentBackend() Backend {
+	return backend.Load().(backendBox).Backend
+}
+
+// NewMapBackend returns a backend that keys stores by request pointer in a
+// map guarded by a single lock. This is the default.
+func NewMapBackend() Backend {
+	return &mapBackend{m: make(map[*http.Request]*Store)}
+}
+
+type mapBackend struct {
+	mu sync.RWMutex
+	m  map[*http.Request]*Store
+}
+
+func (b *mapBackend) Attach(r *http.Request) (*Store, bool) {
+	b.mu.RLock()
+	s := b.m[r]
+	b.mu.RUnlock()
+	if s != nil {
+		return s, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if s = b.m[r]; s != nil {
+		return s, false
+	}
+	s = newStoreFor(r)
+	b.m[r] = s
+	return s, true
+}
+
+func (b *mapBackend) Lookup(r *http.Request) *Store {
+	b.mu.RLock()
+	s := b.m[r]
+	b.mu.RUnlock()
+	return s
+}
+
+func (b *mapBackend) Release(r *http.Request) *Store {
+	b.mu.Lock()
+	s := b.m[r]
+	delete(b.m, r)
+	b.mu.Unlock()
+	return s
+}
+
+func (b *mapBackend) Range(f func(r *http.Request, s *Store) bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for r, s := range b.m {
+		if !f(r, s) {
+			return
+		}
+	}
+}
+
+// NewSyncMapBackend returns a backend that keys stores by request pointer in
+// a sync.Map. It avoids lock contention when many requests are served
+// concurrently, at the cost of more memory per request.
+func NewSyncMapBackend() Backend {
+	return &syncMapBackend{}
+}
+
+type syncMapBackend struct {
+	m sync.Map
+}
+
+func (b *syncMapBackend) Attach(r *http.Request) (*Store, bool) {
+	if s, ok := b.m.Load(r); ok {
+		return s.(*Store), false
+	}
+	s := newStoreFor(r)
+	actual, loaded := b.m.LoadOrStore(r, s)
+	if loaded {
+		s.mu.Lock()
+		s.cleared = true
+		s.mu.Unlock()
+		recycle(s)
+	}
+	return actual.(*Store), !loaded
+}
+
+func (b *syncMapBackend) Lookup(r *http.Request) *Store {
+	if s, ok := b.m.Load(r); ok {
+		return s.(*Store)
+	}
+	return nil
+}
+
+func (b *syncMapBackend) Release(r *http.Request) *Store {
+	if s, ok := b.m.LoadAndDelete(r); ok {
+		return s.(*Store)
+	}
+	return nil
+}
+
+func (b *syncMapBackend) Range(f func(r *http.Request, s *Store) bool) {
+	b.m.Range(func(k, v interface{}) bool {
+		return f(k.(*http.Request), v.(*Store))
+	})
+}
+
+// NewShardedBackend returns a backend that keys stores by request pointer in
+// n maps, each guarded by its own lock, so that servers handling many
+// concurrent requests don't contend on a single lock. If n <= 0,
+// runtime.GOMAXPROCS(0)*4 shards are used.
+func NewShardedBackend(n int) Backend {
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0) * 4
+	}
+	b := &shardedBackend{shards: make([]mapBackend, n)}
+	for i := range b.shards {
+		b.shards[i].m = make(map[*http.Request]*Store)
+	}
+	return b
+}
+
+type shardedBackend struct {
+	shards []mapBackend
+}
+
+// shard returns the shard of a request. Pointers are multiplied by a large
+// odd constant so that their aligned low bits don't all land in the same
+// shard.
+func (b *shardedBackend) shard(r *http.Request) *mapBackend {
+	h := uint64(uintptr(unsafe.Pointer(r))) * 0x9e3779b97f4a7c15
+	return &b.shards[(h>>32)%uint64(len(b.shards))]
+}
+
+func (b *shardedBackend) Attach(r *http.Request) (*Store, bool) {
+	return b.shard(r).Attach(r)
+}
+
+func (b *shardedBackend) Lookup(r *http.Request) *Store {
+	return b.shard(r).Lookup(r)
+}
+
+func (b *shardedBackend) Release(r *http.Request) *Store {
+	return b.shard(r).Release(r)
+}
+
+func (b *shardedBackend) Range(f func(r *http.Request, s *Store) bool) {
+	for i := range b.shards {
+		more := true
+		b.shards[i].Range(func(r *http.Request, s *Store) bool {
+			more = f(r, s)
+			return more
+		})
+		if !more {
+			return
+		}
+	}
+}
+
+// NewCarrierBackend returns a backend that keys stores by the value carrier
+// returns for a request, so that requests with the same carrier share a
+// store. This suits frameworks that hand out shallow copies of requests:
+// the carrier can be a value they all share, such as a pointer stored in the
+// request context by an outer middleware.
+//
+// Requests for which carrier returns nil, or a value that can't be used as
+// a map key, are keyed by pointer.
+func NewCarrierBackend(carrier func(r *http.Request) interface{}) Backend {
+	return &carrierBackend{
+		carrier: carrier,
+		m:       make(map[interface{}]carried),
+	}
+}
+
+// NewBodyBackend returns a backend that keys stores by request body, so
+// that copies of a request sharing its body share its values. Requests
+// without a body are keyed by pointer. Middleware that replaces the body
+// hides the values stored before.
+func NewBodyBackend() Backend {
+	return NewCarrierBackend(func(r *http.Request) interface{} {
+		if r.Body == nil || r.Body == http.NoBody {
+			return nil
+		}
+		return r.Body
+	})
+}
+
+type carrierBackend struct {
+	carrier func(r *http.Request) interface{}
+	mu      sync.RWMutex
+	m       map[interface{}]carried
+}
+
+// carried is a store along with the request that created it, for Range.
+type carried struct {
+	r *http.Request
+	s *Store
+}
+
+func (b *carrierBackend) key(r *http.Request) interface{} {
+	k := b.carrier(r)
+	if k == nil || !reflect.TypeOf(k).Comparable() {
+		return r
+	}
+	return k
+}
+
+func (b *carrierBackend) Attach(r *http.Request) (*Store, bool) {
+	k := b.key(r)
+	b.mu.RLock()
+	c, ok := b.m[k]
+	b.mu.RUnlock()
+	if ok {
+		return c.s, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c, ok = b.m[k]; ok {
+		return c.s, false
+	}
+	// Stores are shared by several requests: they can't be owned by one.
+	s := NewStore()
+	b.m[k] = carried{r, s}
+	return s, true
+}
+
+func (b *carrierBackend) Lookup(r *http.Request) *Store {
+	k := b.key(r)
+	b.mu.RLock()
+	c := b.m[k]
+	b.mu.RUnlock()
+	return c.s
+}
+
+func (b *carrierBackend) Release(r *http.Request) *Store {
+	k := b.key(r)
+	b.mu.Lock()
+	c := b.m[k]
+	delete(b.m, k)
+	b.mu.Unlock()
+	return c.s
+}
+
+func (b *carrierBackend) Range(f func(r *http.Request, s *Store) bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, c := range b.m {
+		if !f(c.r, c.s) {
+			return
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package context
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func testBackend(t *testing.T, b Backend) {
+	SetBackend(b)
+	defer SetBackend(NewMapBackend())
+
+	r1, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	r2, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+
+	if b.Lookup(r1) != nil {
+		t.Error("Expected no store for a new request")
+	}
+	Set(r1, key1, "1")
+	Set(r2, key1, "2")
+	if Get(r1, key1) != "1" || Get(r2, key1) != "2" {
+		t.Errorf("Unexpected values %v %v", GetAll(r1), GetAll(r2))
+	}
+	if s, created := b.Attach(r1); created || s != b.Lookup(r1) {
+		t.Error("Expected Attach to return the existing store")
+	}
+	if n := storeCount(); n != 2 {
+		t.Errorf("Expected 2 stores, got %d", n)
+	}
+
+	Clear(r1)
+	if b.Lookup(r1) != nil {
+		t.Error("Expected Clear to release the store")
+	}
+	if n := Purge(0); n != 1 {
+		t.Errorf("Expected Purge to remove 1 store, got %d", n)
+	}
+	if Get(r2, key1) != nil {
+		t.Error("Expected Purge to remove values")
+	}
+}
+
+func TestMapBackend(t *testing.T) {
+	testBackend(t, NewMapBackend())
+}
+
+func TestSyncMapBackend(t *testing.T) {
+	testBackend(t, NewSyncMapBackend())
+}
+
+func TestShardedBackend(t *testing.T) {
+	testBackend(t, NewShardedBackend(4))
+	testBackend(t, NewShardedBackend(0))
+}
+
+func TestCarrierBackend(t *testing.T) {
+	testBackend(t, NewCarrierBackend(func(r *http.Request) interface{} {
+		return nil
+	}))
+	testBackend(t, NewBodyBackend())
+
+	SetBackend(NewBodyBackend())
+	defer SetBackend(NewMapBackend())
+
+	r, _ := http.NewRequest("POST", "http://localhost:8080/", strings.NewReader("body"))
+	defer Clear(r)
+	Set(r, key1, "1")
+	shallow := new(http.Request)
+	*shallow = *r
+	if v := Get(shallow, key1); v != "1" {
+		t.Errorf("Expected a copy sharing the body to share values, got %v", v)
+	}
+	Set(shallow, key2, "2")
+	if v := Get(r, key2); v != "2" {
+		t.Errorf("Expected writes through the copy to be shared, got %v", v)
+	}
+}
+
+func TestCurrentBackend(t *testing.T) {
+	prev := CurrentBackend()
+	defer SetBackend(prev)
+	b := NewSyncMapBackend()
+	SetBackend(b)
+	if CurrentBackend() != b {
+		t.Error("Expected CurrentBackend to return the backend set last")
+	}
+}
@@ -0,0 +1,158 @@
+package context
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Limits of the W3C Baggage specification.
+const (
+	maxBaggageMembers = 64
+	maxBaggageBytes   = 8192
+)
+
+// ErrInvalidBaggage is returned by ExtractBaggage for a malformed baggage
+// header.
+var ErrInvalidBaggage = errors.New("context: invalid baggage header")
+
+// ExtractBaggage parses the W3C "baggage" headers of a request and stores
+// their members, found with Baggage. Member properties are dropped.
+//
+// Malformed members are skipped, and reported by ErrInvalidBaggage after
+// storing the valid ones. Members beyond the limits of the specification
+// are dropped.
+func ExtractBaggage(r *http.Request) error {
+	var bad bool
+	members := make(map[string]string)
+	size := 0
+	for _, h := range r.Header.Values("baggage") {
+		for _, m := range strings.Split(h, ",") {
+			m = strings.TrimSpace(m)
+			if m == "" {
+				continue
+			}
+			size += len(m)
+			if len(members) >= maxBaggageMembers || size > maxBaggageBytes {
+				break
+			}
+			if i := strings.IndexByte(m, ';'); i >= 0 {
+				m = m[:i]
+			}
+			k, v, ok := strings.Cut(m, "=")
+			k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+			if !ok || !isToken(k) {
+				bad = true
+				continue
+			}
+			value, err := url.PathUnescape(v)
+			if err != nil {
+				bad = true
+				continue
+			}
+			members[k] = value
+		}
+	}
+	if len(members) > 0 {
+		WithStore(r, func(s MutableStore) {
+			old, _ := s.Get(baggageKey).(map[string]string)
+			for k, v := range old {
+				if _, ok := members[k]; !ok {
+					members[k] = v
+				}
+			}
+			s.Set(baggageKey, members)
+		})
+	}
+	if bad {
+		return ErrInvalidBaggage
+	}
+	return nil
+}
+
+// Baggage returns the value of a baggage member of a request, and whether
+// it is present.
+func Baggage(r *http.Request, key string) (string, bool) {
+	members, _ := Get(r, baggageKey).(map[string]string)
+	v, ok := members[key]
+	return v, ok
+}
+
+// SetBaggage sets a baggage member of a request, to be sent by
+// InjectBaggage.
+func SetBaggage(r *http.Request, key, value string) {
+	WithStore(r, func(s MutableStore) {
+		old, _ := s.Get(baggageKey).(map[string]string)
+		// Maps returned to callers are never modified: copy.
+		members := make(map[string]string, len(old)+1)
+		for k, v := range old {
+			members[k] = v
+		}
+		members[key] = value
+		s.Set(baggageKey, members)
+	})
+}
+
+// InjectBaggage sets the "baggage" header of out, usually the headers of an
+// outgoing request, to the given baggage members of r, or all of them if no
+// keys are given. Keys that are not present are skipped, and the header is
+// left alone if none is.
+func InjectBaggage(r *http.Request, out http.Header, keys ...string) {
+	members, _ := Get(r, baggageKey).(map[string]string)
+	if len(keys) == 0 {
+		for k := range members {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+	}
+	var b strings.Builder
+	for _, k := range keys {
+		v, ok := members[k]
+		if !ok || !isToken(k) {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(escapeBaggage(v))
+	}
+	if b.Len() > 0 {
+		out.Set("baggage", b.String())
+	}
+}
+
+// isToken reports whether s is an RFC 7230 token.
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte(`"(),/:;<=>?@[\]{}`, c) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// escapeBaggage percent-encodes the characters that are not allowed in
+// baggage values, and percent signs.
+func escapeBaggage(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c >= 0x7f || c == '"' || c == ',' || c == ';' || c == '\\' || c == '%' {
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&15])
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,37 @@
+package context
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestBaggage(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	r.Header.Add("baggage", "userId=alice, serverNode = DF%2028;prop=1")
+	r.Header.Add("baggage", "isProduction=false,bad key=1")
+
+	if err := ExtractBaggage(r); err != ErrInvalidBaggage {
+		t.Errorf("Expected ErrInvalidBaggage, got %v", err)
+	}
+	for k, want := range map[string]string{"userId": "alice", "serverNode": "DF 28", "isProduction": "false"} {
+		if v, ok := Baggage(r, k); !ok || v != want {
+			t.Errorf("Expected %s=%q, got %q", k, want, v)
+		}
+	}
+	if _, ok := Baggage(r, "bad key"); ok {
+		t.Error("Expected the malformed member to be skipped")
+	}
+
+	SetBaggage(r, "tenant", "a,b")
+	out := http.Header{}
+	InjectBaggage(r, out, "tenant", "serverNode", "missing")
+	if got := out.Get("baggage"); got != "tenant=a%2Cb,serverNode=DF%2028" {
+		t.Errorf("Unexpected header %q", got)
+	}
+	out = http.Header{}
+	InjectBaggage(r, out)
+	if got := out.Get("baggage"); got != "isProduction=false,serverNode=DF%2028,tenant=a%2Cb,userId=alice" {
+		t.Errorf("Unexpected header %q", got)
+	}
+}
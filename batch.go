@@ -0,0 +1,82 @@
+package context
+
+import (
+	"net/http"
+)
+
+// MutableStore gives access to the values of a request while its store is
+// locked. It is only valid during the call to WithStore that provided it.
+type MutableStore interface {
+	// Get returns the value stored for key.
+	Get(key interface{}) interface{}
+	// GetOk returns the value stored for key and whether it was present.
+	GetOk(key interface{}) (interface{}, bool)
+	// Set stores a value for key, like the package-level Set.
+	Set(key, val interface{})
+	// SetE stores a value for key, like the package-level SetE.
+	SetE(key, val interface{}) error
+	// Delete removes the value stored for key.
+	Delete(key interface{})
+	// Len returns the number of values stored.
+	Len() int
+}
+
+// WithStore calls f with the store of a given request, locked once for the
+// whole call. Middleware reading and writing several values this way avoids
+// taking the lock for every one of them, and other goroutines never see the
+// store halfway through f.
+//
+// f must not call other functions of this package for the same request, nor
+// keep the MutableStore after it returns. Set and Delete are traced and
+// recorded like their package-level counterparts, with the store locked:
+// trace hooks must not call this package for the request either.
+func WithStore(r *http.Request, f func(s MutableStore)) {
+	s := attach(r)
+	defer s.mu.Unlock()
+	f(lockedStore{r, s})
+}
+
+// lockedStore implements MutableStore for the store of r locked for writing.
+type lockedStore struct {
+	r *http.Request
+	s *Store
+}
+
+func (l lockedStore) Get(key interface{}) interface{} {
+	v, _ := l.GetOk(key)
+	return v
+}
+
+func (l lockedStore) GetOk(key interface{}) (interface{}, bool) {
+	v, ok := l.s.getKey(key)
+	if ok && trackUse() {
+		l.s.touch(key)
+	}
+	return v, ok
+}
+
+func (l lockedStore) Set(key, val interface{}) {
+	_ = l.SetE(key, val)
+}
+
+func (l lockedStore) SetE(key, val interface{}) error {
+	traceSet(l.r, key)
+	observe(OpSet, l.r, key, val)
+	err := l.s.set(key, val)
+	if err == nil {
+		l.s.recordLocked(key, false)
+	}
+	return err
+}
+
+func (l lockedStore) Delete(key interface{}) {
+	traceDelete(l.r, key)
+	observe(OpDelete, l.r, key, nil)
+	if l.s.remove(key) == nil {
+		l.s.recordLocked(key, true)
+	}
+}
+
+func (l lockedStore) Len() int {
+	return l.s.count()
+}
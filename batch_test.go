@@ -0,0 +1,68 @@
+package context
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestWithStore(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+
+	Set(r, key1, 1)
+	WithStore(r, func(s MutableStore) {
+		s.Set(key1, s.Get(key1).(int)+1)
+		s.Set(key2, "2")
+		s.Set("tmp", true)
+		s.Delete("tmp")
+		if n := s.Len(); n != 2 {
+			t.Errorf("Expected 2 values, got %d", n)
+		}
+		if _, ok := s.GetOk("tmp"); ok {
+			t.Error("Expected tmp to be deleted")
+		}
+	})
+	if Get(r, key1) != 2 || Get(r, key2) != "2" {
+		t.Errorf("Unexpected values %v", GetAll(r))
+	}
+
+	SetMaxEntries(2)
+	SetEvictionPolicy(RejectNew)
+	defer func() {
+		SetMaxEntries(0)
+		SetEvictionPolicy(EvictLRU)
+	}()
+	WithStore(r, func(s MutableStore) {
+		if err := s.SetE("third", 3); err != ErrMaxEntries {
+			t.Errorf("Expected ErrMaxEntries, got %v", err)
+		}
+	})
+}
+
+func TestWithStoreTraced(t *testing.T) {
+	var ops []string
+	SetTraceHooks(Hooks{
+		OnSet: func(r *http.Request, key interface{}, caller string) {
+			ops = append(ops, "set")
+		},
+		OnDelete: func(r *http.Request, key interface{}, caller string) {
+			ops = append(ops, "delete")
+		},
+	})
+	defer SetTraceHooks(Hooks{})
+	EnableHistory(true)
+	defer EnableHistory(false)
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	WithStore(r, func(s MutableStore) {
+		s.Set(key1, "1")
+		s.Delete(key1)
+	})
+	if len(ops) != 2 || ops[0] != "set" || ops[1] != "delete" {
+		t.Errorf("Expected the changes to be traced, got %v", ops)
+	}
+	if h := History(r, key1); len(h) != 2 || h[0].Deleted || !h[1].Deleted {
+		t.Errorf("Expected the changes to be recorded, got %+v", h)
+	}
+}
@@ -0,0 +1,85 @@
+package context
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+)
+
+var (
+	// ErrBodyTooLarge is returned by BufferBody when the body is longer
+	// than the given limit.
+	ErrBodyTooLarge = errors.New("context: request body too large")
+	// ErrBodyNotBuffered is returned by RewindBody when BufferBody was not
+	// called for the request.
+	ErrBodyNotBuffered = errors.New("context: request body not buffered")
+)
+
+// BufferBody reads the body of a request into memory, so that it can be
+// read again after RewindBody. It fails with ErrBodyTooLarge if the body is
+// longer than maxBytes. The original body is closed and replaced by one
+// reading from the buffer, which is released when the request is cleared.
+//
+// This lets a middleware read the body, for example to verify a signature,
+// and still hand it to the handler:
+//
+//	if err := context.BufferBody(r, 1<<20); err != nil {
+//		// ...
+//	}
+//	verify(r.Body)
+//	context.RewindBody(r)
+//
+// If BufferBody fails, the body still reads from the start: the part read so
+// far is put back in front of the rest. Calling BufferBody again only
+// rewinds the body.
+func BufferBody(r *http.Request, maxBytes int64) error {
+	if _, ok := GetOk(r, bodyKey); ok {
+		return RewindBody(r)
+	}
+	var buf []byte
+	if r.Body != nil && r.Body != http.NoBody {
+		body := r.Body
+		var err error
+		buf, err = io.ReadAll(io.LimitReader(body, maxBytes+1))
+		if err == nil && int64(len(buf)) > maxBytes {
+			err = ErrBodyTooLarge
+		}
+		if err != nil {
+			r.Body = &unreadBody{io.MultiReader(bytes.NewReader(buf), body), body}
+			return err
+		}
+		body.Close()
+	}
+	if err := SetE(r, bodyKey, buf); err != nil {
+		setBody(r, buf)
+		return err
+	}
+	return RewindBody(r)
+}
+
+// unreadBody is a body with the part already read put back in front.
+type unreadBody struct {
+	io.Reader
+	io.Closer
+}
+
+// RewindBody replaces the body of a request buffered with BufferBody by a
+// new one reading from the start.
+func RewindBody(r *http.Request) error {
+	v, ok := GetOk(r, bodyKey)
+	if !ok {
+		return ErrBodyNotBuffered
+	}
+	setBody(r, v.([]byte))
+	return nil
+}
+
+// setBody replaces the body of r by one reading buf.
+func setBody(r *http.Request, buf []byte) {
+	if buf == nil {
+		r.Body = http.NoBody
+	} else {
+		r.Body = io.NopCloser(bytes.NewReader(buf))
+	}
+}
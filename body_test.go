@@ -0,0 +1,67 @@
+package context
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestBufferBody(t *testing.T) {
+	r, _ := http.NewRequest("POST", "http://localhost:8080/", strings.NewReader("payload"))
+	defer Clear(r)
+
+	if err := RewindBody(r); err != ErrBodyNotBuffered {
+		t.Errorf("Expected ErrBodyNotBuffered, got %v", err)
+	}
+	if err := BufferBody(r, 1024); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		b, _ := io.ReadAll(r.Body)
+		if string(b) != "payload" {
+			t.Errorf("Expected payload, got %q", b)
+		}
+		if err := RewindBody(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	Clear(r)
+	if err := RewindBody(r); err != ErrBodyNotBuffered {
+		t.Errorf("Expected the buffer to be cleared, got %v", err)
+	}
+}
+
+func TestBufferBodyLimit(t *testing.T) {
+	r, _ := http.NewRequest("POST", "http://localhost:8080/", strings.NewReader("payload"))
+	defer Clear(r)
+
+	if err := BufferBody(r, 3); err != ErrBodyTooLarge {
+		t.Errorf("Expected ErrBodyTooLarge, got %v", err)
+	}
+	if b, _ := io.ReadAll(r.Body); string(b) != "payload" {
+		t.Errorf("Expected the whole body to be readable, got %q", b)
+	}
+
+	empty, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(empty)
+	if err := BufferBody(empty, 3); err != nil {
+		t.Fatal(err)
+	}
+	if empty.Body != http.NoBody {
+		t.Errorf("Expected http.NoBody, got %v", empty.Body)
+	}
+}
+
+func TestBufferBodyFrozen(t *testing.T) {
+	r, _ := http.NewRequest("POST", "http://localhost:8080/", strings.NewReader("payload"))
+	defer Clear(r)
+	Freeze(r)
+	if err := BufferBody(r, 1<<10); err != ErrFrozen {
+		t.Errorf("Expected ErrFrozen, got %v", err)
+	}
+	if b, _ := io.ReadAll(r.Body); string(b) != "payload" {
+		t.Errorf("Expected the body to be readable, got %q", b)
+	}
+}
@@ -0,0 +1,123 @@
+package context
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultCacheSize is the number of entries of a RequestCache unless
+// SetCacheSize was called.
+const defaultCacheSize = 1024
+
+// cacheSize is the size set with SetCacheSize.
+var cacheSize int64
+
+// SetCacheSize sets the maximum number of entries of the caches returned by
+// Cache that are created afterwards. If n <= 0, the default of 1024 is used.
+func SetCacheSize(n int) {
+	atomic.StoreInt64(&cacheSize, int64(n))
+}
+
+// RequestCache memoizes results for the duration of a request, apart from
+// the values of the request: its keys don't clash with those of Set, and its
+// entries are left out of GetAll. When it is full, Put removes the least
+// recently used entry. It may be used by several goroutines.
+type RequestCache struct {
+	mu      sync.Mutex
+	max     int
+	entries map[interface{}]*list.Element
+	order   list.List
+}
+
+// cacheEntry is an entry of a RequestCache, in the order list.
+type cacheEntry struct {
+	key, val interface{}
+}
+
+// Cache returns the cache of a request, creating it on first use. It is
+// removed along with the values of the request by Clear.
+//
+// The cache is stored even if the request is frozen, and regardless of the
+// key policy and of SetMaxEntries and SetMemoryLimit. Cache panics if it
+// can't be stored anyway, as for a SetMaxValueSize smaller than an empty
+// cache: a cache created anew on every call would memoize nothing.
+//
+//	c := context.Cache(r)
+//	if v, ok := c.Get(id); ok {
+//		return v.(*Product)
+//	}
+//	p := loadProduct(id)
+//	c.Put(id, p)
+func Cache(r *http.Request) *RequestCache {
+	var (
+		c   *RequestCache
+		err error
+	)
+	WithStore(r, func(s MutableStore) {
+		c, _ = s.Get(cacheKey).(*RequestCache)
+		if c == nil {
+			c = newRequestCache()
+			err = s.SetE(cacheKey, c)
+		}
+	})
+	if err != nil {
+		panic("context: can't store the request cache: " + err.Error())
+	}
+	return c
+}
+
+func newRequestCache() *RequestCache {
+	max := int(loadInt(&cacheSize))
+	if max <= 0 {
+		max = defaultCacheSize
+	}
+	return &RequestCache{max: max, entries: make(map[interface{}]*list.Element)}
+}
+
+// Get returns the value cached for key and whether it was present.
+func (c *RequestCache) Get(key interface{}) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*cacheEntry).val, true
+}
+
+// Put caches a value for key.
+func (c *RequestCache) Put(key, val interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*cacheEntry).val = val
+		c.order.MoveToFront(e)
+		return
+	}
+	if len(c.entries) >= c.max {
+		oldest := c.order.Back()
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+		c.order.Remove(oldest)
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key, val})
+}
+
+// Delete removes the value cached for key.
+func (c *RequestCache) Delete(key interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		delete(c.entries, key)
+		c.order.Remove(e)
+	}
+}
+
+// Len returns the number of cached values.
+func (c *RequestCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
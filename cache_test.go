@@ -0,0 +1,67 @@
+package context
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	Set(r, key1, "value")
+	c := Cache(r)
+	if Cache(r) != c {
+		t.Fatal("Expected the same cache for the request")
+	}
+	c.Put(key1, "cached")
+	if v, ok := c.Get(key1); !ok || v != "cached" || Get(r, key1) != "value" {
+		t.Errorf("Expected the cache apart from the values, got %v", v)
+	}
+	if all := GetAll(r); len(all) != 1 {
+		t.Errorf("Expected the cache to be left out of GetAll, got %v", all)
+	}
+	c.Delete(key1)
+	if _, ok := c.Get(key1); ok || c.Len() != 0 {
+		t.Error("Expected the entry to be deleted")
+	}
+
+	Clear(r)
+	if Cache(r) == c {
+		t.Error("Expected Clear to remove the cache")
+	}
+}
+
+func TestCacheSize(t *testing.T) {
+	SetCacheSize(2)
+	defer SetCacheSize(0)
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	c := Cache(r)
+	c.Put(1, "a")
+	c.Put(2, "b")
+	c.Get(1)
+	c.Put(3, "c")
+	if c.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", c.Len())
+	}
+	if _, ok := c.Get(2); ok {
+		t.Error("Expected the least recently used entry to be removed")
+	}
+	if _, ok := c.Get(1); !ok {
+		t.Error("Expected the recently used entry to be kept")
+	}
+}
+
+func TestCacheRefused(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	SetMaxValueSize(1)
+	defer SetMaxValueSize(0)
+	defer func() {
+		if p, _ := recover().(string); !strings.Contains(p, ErrValueTooLarge.Error()) {
+			t.Errorf("Expected a panic with ErrValueTooLarge, got %q", p)
+		}
+	}()
+	Cache(r)
+}
@@ -0,0 +1,100 @@
+package context
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// children counts the stores with a parent, so that reads only look for
+// parents when there may be some.
+var children int64
+
+// Child returns a shallow copy of r whose values are inherited from r: reads
+// that find no value for the copy fall back to r, while writes, deletions and
+// Clear only affect the copy. Internal sub-requests, such as those a batch
+// endpoint dispatches to per-item handlers, see the values of the request
+// without copying them:
+//
+//	for _, item := range items {
+//		sub := context.Child(r)
+//		itemHandler.ServeHTTP(w, sub)
+//		context.Clear(sub)
+//	}
+//
+// Deleting an inherited value from the copy hides it: the copy no longer
+// falls back to r for that key until it is set again.
+//
+// Child requests must be cleared like any other. Values inherited from r are
+// read when requested, so they are gone once r is cleared. Dump only shows
+// the values of the child itself.
+func Child(r *http.Request) *http.Request {
+	c := unaliased(r)
+	s := attach(c)
+	s.parent = r
+	s.mu.Unlock()
+	atomic.AddInt64(&children, 1)
+	return c
+}
+
+// parentOf returns the request r inherits values from, or nil.
+func parentOf(r *http.Request) *http.Request {
+	if loadInt(&children) == 0 {
+		return nil
+	}
+	s := lookup(r)
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.ownedBy(r) {
+		return nil
+	}
+	return s.parent
+}
+
+// parentFor returns the request r inherits the value of key from, or nil if
+// there is none or key was deleted from r.
+func parentFor(r *http.Request, key interface{}) *http.Request {
+	if loadInt(&children) == 0 {
+		return nil
+	}
+	s := lookup(r)
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.ownedBy(r) {
+		return nil
+	}
+	if _, ok := s.hidden[key]; ok {
+		return nil
+	}
+	return s.parent
+}
+
+// inherited returns the request r inherits values from, or nil, and a copy
+// of the keys deleted from r, which must not be read from it.
+func inherited(r *http.Request) (*http.Request, map[interface{}]struct{}) {
+	if loadInt(&children) == 0 {
+		return nil, nil
+	}
+	s := lookup(r)
+	if s == nil {
+		return nil, nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.ownedBy(r) || s.parent == nil {
+		return nil, nil
+	}
+	var hidden map[interface{}]struct{}
+	if len(s.hidden) > 0 {
+		hidden = make(map[interface{}]struct{}, len(s.hidden))
+		for k := range s.hidden {
+			hidden[k] = struct{}{}
+		}
+	}
+	return s.parent, hidden
+}
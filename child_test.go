@@ -0,0 +1,79 @@
+package context
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+func TestChild(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/batch", nil)
+	defer Clear(r)
+	k := NewKey[int]("count")
+	Set(r, key1, "1")
+	SetS(r, "user", "gopher")
+	k.Set(r, 1)
+
+	c := Child(r)
+	if Get(c, key1) != "1" || GetS(c, "user") != "gopher" || k.Get(c) != 1 {
+		t.Errorf("Expected the child to inherit values, got %v", GetAll(c))
+	}
+	Set(c, key1, "child")
+	Set(c, key2, "2")
+	if Get(c, key1) != "child" || Get(r, key1) != "1" {
+		t.Error("Expected child writes to shadow the parent values")
+	}
+	if _, ok := GetOk(r, key2); ok {
+		t.Error("Expected child writes not to reach the parent")
+	}
+	if all := GetAll(c); len(all) != 4 || all[key1] != "child" || all["user"] != "gopher" {
+		t.Errorf("Unexpected values %v", all)
+	}
+
+	Clear(c)
+	if Get(r, key1) != "1" {
+		t.Error("Expected clearing the child to keep the parent values")
+	}
+	if n := atomic.LoadInt64(&children); n != 0 {
+		t.Errorf("Expected no child stores left, got %d", n)
+	}
+}
+
+func TestChildDelete(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/batch", nil)
+	defer Clear(r)
+	k := NewKey[int]("count")
+	Set(r, key1, "1")
+	SetS(r, "user", "gopher")
+	k.Set(r, 1)
+
+	c := Child(r)
+	defer Clear(c)
+	Delete(c, key1)
+	Delete(c, "user")
+	k.Delete(c)
+	if _, ok := GetOk(c, key1); ok || Has(c, key1) {
+		t.Error("Expected a deleted inherited value to be hidden")
+	}
+	if GetS(c, "user") != nil {
+		t.Error("Expected a deleted inherited string key to be hidden")
+	}
+	if _, ok := k.GetOk(c); ok {
+		t.Error("Expected a deleted inherited typed key to be hidden")
+	}
+	if all := GetAll(c); len(all) != 0 || Len(c) != 0 || len(Keys(c)) != 0 {
+		t.Errorf("Expected no values, got %v", all)
+	}
+	if Get(r, key1) != "1" || GetS(r, "user") != "gopher" {
+		t.Error("Expected the parent to keep its values")
+	}
+
+	Set(c, key1, "child")
+	if Get(c, key1) != "child" {
+		t.Error("Expected setting a deleted key to store it again")
+	}
+	Delete(c, key1)
+	if _, ok := GetOk(c, key1); ok {
+		t.Error("Expected the key to be hidden again")
+	}
+}
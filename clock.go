@@ -0,0 +1,78 @@
+package context
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Clock tells the time to the package: the age of requests for Purge and
+// DebugHandler, StartTime and Elapsed, the durations of TimingHandler and
+// client traces, and the times of History.
+//
+// A Clock may also implement AfterClock, to control the delays waited by the
+// package as well.
+type Clock interface {
+	Now() time.Time
+}
+
+// AfterClock is implemented by clocks that also control delays, such as the
+// Grace of StrictMode. Without it, delays are waited in real time.
+type AfterClock interface {
+	Clock
+	// After returns a channel receiving the time once d elapsed, like
+	// time.After.
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the default Clock.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// clock holds the Clock set with SetClock. It is read whenever a store is
+// created, so it is accessed atomically.
+var clock atomic.Value
+
+func init() {
+	clock.Store(clockBox{systemClock{}})
+}
+
+// clockBox gives every clock the same concrete type in atomic.Value.
+type clockBox struct {
+	Clock
+}
+
+// SetClock replaces the clock of the package, so that tests can control
+// time instead of sleeping. A nil clock restores the system clock.
+func SetClock(c Clock) {
+	if c == nil {
+		c = systemClock{}
+	}
+	clock.Store(clockBox{c})
+}
+
+// Now returns the current time according to the clock of the package, so
+// that packages built on this one follow SetClock too.
+func Now() time.Time {
+	return now()
+}
+
+// now returns the current time according to the clock.
+func now() time.Time {
+	return clock.Load().(clockBox).Now()
+}
+
+// after waits for d according to the clock, like time.After.
+func after(d time.Duration) <-chan time.Time {
+	if c, ok := clock.Load().(clockBox).Clock.(AfterClock); ok {
+		return c.After(d)
+	}
+	return time.After(d)
+}
+
+// since returns the time elapsed since t according to the clock.
+func since(t time.Time) time.Duration {
+	return now().Sub(t)
+}
@@ -0,0 +1,49 @@
+package context
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// manualClock is a Clock advanced by tests.
+type manualClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *manualClock) advance(d time.Duration) {
+	c.mu.Lock()
+	c.t = c.t.Add(d)
+	c.mu.Unlock()
+}
+
+func TestSetClock(t *testing.T) {
+	c := &manualClock{t: time.Date(2012, 1, 1, 0, 0, 0, 0, time.UTC)}
+	SetClock(c)
+	defer SetClock(nil)
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	Set(r, key1, "1")
+	if got := StartTime(r); !got.Equal(c.Now()) {
+		t.Errorf("Expected the start time to come from the clock, got %v", got)
+	}
+	c.advance(90 * time.Second)
+	if d := Elapsed(r); d != 90*time.Second {
+		t.Errorf("Expected 90s elapsed, got %v", d)
+	}
+	if n := Purge(100); n != 0 {
+		t.Errorf("Expected no request to be purged, got %d", n)
+	}
+	if n := Purge(60); n != 1 {
+		t.Errorf("Expected the request to be purged, got %d", n)
+	}
+}
@@ -0,0 +1,48 @@
+package context
+
+import (
+	"net/http"
+	"reflect"
+)
+
+// Snapshot returns a copy of all values stored for the request that can be
+// handed to goroutines outliving the request. Nil is returned for invalid
+// requests.
+//
+// Unlike GetAll, stored maps and slices are copied too, so later changes to
+// them by the request handlers are not seen through the snapshot. Values
+// they contain, and values of other types, are shared as is. As for GetAll,
+// values set with SetLazy and not computed yet are left out.
+func Snapshot(r *http.Request) map[interface{}]interface{} {
+	values := GetAll(r)
+	for k, v := range values {
+		values[k] = cloneContainer(v)
+	}
+	return values
+}
+
+// cloneContainer returns a shallow copy of v if it is a map or a slice, and
+// v itself otherwise.
+func cloneContainer(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(rv.Type(), rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), iter.Value())
+		}
+		return c.Interface()
+	case reflect.Slice:
+		if rv.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+		reflect.Copy(c, rv)
+		return c.Interface()
+	}
+	return v
+}
@@ -0,0 +1,35 @@
+package context
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+
+	if Snapshot(r) != nil {
+		t.Error("Expected nil for an invalid request")
+	}
+
+	roles := []string{"admin"}
+	claims := map[string]string{"sub": "gorilla"}
+	Set(r, "roles", roles)
+	Set(r, "claims", claims)
+	Set(r, key1, "1")
+
+	snap := Snapshot(r)
+	roles[0] = "guest"
+	claims["sub"] = "someone"
+	Clear(r)
+
+	if got := snap["roles"].([]string); got[0] != "admin" {
+		t.Errorf("Expected the snapshot to keep admin, got %v", got)
+	}
+	if got := snap["claims"].(map[string]string); got["sub"] != "gorilla" {
+		t.Errorf("Expected the snapshot to keep gorilla, got %v", got)
+	}
+	if snap[key1] != "1" {
+		t.Errorf("Expected 1, got %v", snap[key1])
+	}
+}
@@ -6,98 +6,275 @@ import (
 	"time"
 )
 
-var (
-	mutex sync.RWMutex
-	data  = make(map[*http.Request]map[interface{}]interface{})
-	datat = make(map[*http.Request]int64)
+// mutex guards the package configuration that isn't read on every call.
+var mutex sync.RWMutex
+
+// internalKey is the type of the keys used by the package itself.
+type internalKey int
+
+const (
+	bodyKey internalKey = iota
+	loggerKey
+	clientTraceKey
+	baggageKey
+	principalKey
+	tenantKey
+	flagsKey
+	errorsKey
+	cacheKey
 )
 
+// internalKeyNames are the names of the internal keys, for reports.
+var internalKeyNames = [...]string{
+	bodyKey:        "body",
+	loggerKey:      "logger",
+	clientTraceKey: "client traces",
+	baggageKey:     "baggage",
+	principalKey:   "user",
+	tenantKey:      "tenant",
+	flagsKey:       "flags",
+	errorsKey:      "errors",
+	cacheKey:       "cache",
+}
+
+func (k internalKey) String() string {
+	return internalKeyNames[k]
+}
+
+// isInternal reports whether key is one of the internal keys.
+func isInternal(key interface{}) bool {
+	_, ok := key.(internalKey)
+	return ok
+}
+
 // Set stores a value for a given key in a given request.
+//
+// If the value is refused by the limits configured with SetMaxEntries,
+// SetMemoryLimit or SetMaxValueSize, by the key policy set with
+// SetKeyPolicy, because its type doesn't match the one registered with
+// RegisterKey, or because the request was frozen with Freeze, it is
+// silently dropped. Use SetE to find out.
 func Set(r *http.Request, key, val interface{}) {
-	mutex.Lock()
-	if data[r] == nil {
-		data[r] = make(map[interface{}]interface{})
-		datat[r] = time.Now().Unix()
+	_ = SetE(r, key, val)
+}
+
+// SetE stores a value for a given key in a given request, like Set, and
+// returns an error if the new entry was refused.
+func SetE(r *http.Request, key, val interface{}) error {
+	traceSet(r, key)
+	observe(OpSet, r, key, val)
+	if setStriped(r, key, val) {
+		recordSet(r, key)
+		return nil
+	}
+	s := attach(r)
+	err := s.set(key, val)
+	s.mu.Unlock()
+	if err == nil {
+		recordSet(r, key)
 	}
-	data[r][key] = val
-	mutex.Unlock()
+	return checkFrozen(err)
 }
 
 // Get returns a value stored for a given key in a given request.
 func Get(r *http.Request, key interface{}) interface{} {
-	mutex.RLock()
-	if ctx := data[r]; ctx != nil {
-		value := ctx[key]
-		mutex.RUnlock()
-		return value
-	}
-	mutex.RUnlock()
-	return nil
+	traceGet(r, key)
+	observe(OpGet, r, key, nil)
+	value, _ := getCounted(r, key)
+	return value
 }
 
 // GetOk returns stored value and presence state like multi-value return of map access.
 func GetOk(r *http.Request, key interface{}) (interface{}, bool) {
-	mutex.RLock()
-	if _, ok := data[r]; ok {
-		value, ok := data[r][key]
-		mutex.RUnlock()
-		return value, ok
+	traceGet(r, key)
+	observe(OpGet, r, key, nil)
+	return getCounted(r, key)
+}
+
+// Has reports whether a value is stored for a given key in a given request,
+// for guards that don't need the value, such as checking that a middleware
+// ran. It looks the value up like GetOk and doesn't allocate, nor run the
+// factory of values set with SetLazy.
+func Has(r *http.Request, key interface{}) bool {
+	traceGet(r, key)
+	observe(OpGet, r, key, nil)
+	ok := false
+	for p := r; p != nil && !ok; p = parentFor(p, key) {
+		if s := lookup(p); s != nil {
+			ok = s.has(p, key)
+		}
 	}
-	mutex.RUnlock()
-	return nil, false
+	if !ok {
+		_, ok = getDefault(key)
+	}
+	countGet(key, ok)
+	return ok
 }
 
 // GetAll returns all stored values for the request as a map. Nil is returned for invalid requests.
+//
+// Values stored by the package for its own bookkeeping are left out: see
+// Internal. So are values set with SetLazy and not computed yet: GetAll
+// doesn't run their factory.
 func GetAll(r *http.Request) map[interface{}]interface{} {
-	mutex.RLock()
-	if context, ok := data[r]; ok {
-		result := make(map[interface{}]interface{}, len(context))
-		for k, v := range context {
-			result[k] = v
-		}
-		mutex.RUnlock()
-		return result
-	}
-	mutex.RUnlock()
-	return nil
+	result, _ := storedValues(r, false)
+	return result
 }
 
 // GetAllOk returns all stored values for the request as a map and a boolean value that indicates if
 // the request was registered.
 func GetAllOk(r *http.Request) (map[interface{}]interface{}, bool) {
-	mutex.RLock()
-	context, ok := data[r]
-	result := make(map[interface{}]interface{}, len(context))
-	for k, v := range context {
-		result[k] = v
+	result, ok := storedValues(r, false)
+	if !ok {
+		return make(map[interface{}]interface{}), false
+	}
+	return result, true
+}
+
+// storedValues returns the values stored for a request, with the internal
+// ones if internal is set, and whether the request was registered. The map
+// is nil if it was not. Values set with SetLazy and not computed yet are left
+// out.
+func storedValues(r *http.Request, internal bool) (map[interface{}]interface{}, bool) {
+	return collectValues(r, internal, false)
+}
+
+// collectValues is storedValues, keeping the values set with SetLazy and not
+// computed yet, boxed, if lazy is set.
+func collectValues(r *http.Request, internal, lazy bool) (map[interface{}]interface{}, bool) {
+	// Look the parent up first: the store of r is locked below.
+	p, hidden := inherited(r)
+	vs, s := view(r)
+	if vs == nil {
+		return nil, false
+	}
+	result := make(map[interface{}]interface{}, vs.len())
+	vs.eachRaw(func(k, v interface{}) bool {
+		if pending(v) {
+			if !lazy {
+				return true
+			}
+		} else {
+			v = unbox(v)
+		}
+		if _, ok := k.(internalKey); internal || !ok {
+			result[k] = v
+		}
+		return true
+	})
+	if s != nil {
+		s.mu.RUnlock()
+	}
+	if p != nil {
+		values, _ := collectValues(p, internal, lazy)
+		for k, v := range values {
+			if _, ok := hidden[k]; ok {
+				continue
+			}
+			if _, ok := result[k]; !ok {
+				result[k] = v
+			}
+		}
+	}
+	return result, true
+}
+
+// Internal returns the values stored by the package for its own
+// bookkeeping, such as the user, the tenant or the feature flags of the
+// request, keyed by name. They are left out of GetAll and the functions
+// built on it, so that code iterating over request values only sees its
+// own.
+func Internal(r *http.Request) map[string]interface{} {
+	result := make(map[string]interface{})
+	all, _ := storedValues(r, true)
+	for k, v := range all {
+		if ik, ok := k.(internalKey); ok {
+			result[ik.String()] = v
+		}
 	}
-	mutex.RUnlock()
-	return result, ok
+	return result
 }
 
 // Delete removes a value stored for a given key in a given request.
+//
+// If the request was frozen with Freeze, the value is kept. Use DeleteE to
+// find out.
 func Delete(r *http.Request, key interface{}) {
-	mutex.Lock()
-	if data[r] != nil {
-		delete(data[r], key)
+	_ = DeleteE(r, key)
+}
+
+// DeleteE removes a value stored for a given key in a given request, like
+// Delete, and returns ErrFrozen if the request was frozen.
+func DeleteE(r *http.Request, key interface{}) error {
+	traceDelete(r, key)
+	observe(OpDelete, r, key, nil)
+	s := writeLocked(r)
+	if s == nil {
+		return nil
+	}
+	err := s.remove(key)
+	s.mu.Unlock()
+	if err == nil {
+		recordDelete(r, key)
 	}
-	mutex.Unlock()
+	return checkFrozen(err)
+}
+
+// Pop returns the value stored for a given key in a given request, and
+// removes it, so that the value is consumed at most once even by concurrent
+// callers, as for temporary upload files or one-time redirect targets.
+//
+// If the request was frozen with Freeze, the value is kept and Pop returns
+// nil, false.
+func Pop(r *http.Request, key interface{}) (interface{}, bool) {
+	traceDelete(r, key)
+	observe(OpDelete, r, key, nil)
+	s := writeLocked(r)
+	if s == nil {
+		return nil, false
+	}
+	value, ok := s.getKey(key)
+	if ok && s.remove(key) != nil {
+		value, ok = nil, false
+	}
+	s.mu.Unlock()
+	if ok {
+		recordDelete(r, key)
+	}
+	return value, ok
 }
 
 // Clear removes all values stored for a given request.
 //
 // This is usually called by a handler wrapper to clean up request
 // variables at the end of a request lifetime. See ClearHandler().
+//
+// Functions registered with Defer run after the values are removed.
 func Clear(r *http.Request) {
-	mutex.Lock()
-	clear(r)
-	mutex.Unlock()
+	clearRequest(r, false)
 }
 
-// clear is Clear without the lock.
-func clear(r *http.Request) {
-	delete(data, r)
-	delete(datat, r)
+// clearRequest is Clear, optionally recycling the store afterwards.
+func clearRequest(r *http.Request, reuse bool) {
+	if o := aliasOf(r); o != nil {
+		r = o
+	}
+	traceClear(r)
+	observe(OpClear, r, nil, nil)
+	s := currentBackend().Release(r)
+	if s == nil {
+		return
+	}
+	countMetric(&metricsClear, 1)
+	c := s.clear()
+	if ls := currentListeners(); len(ls) > 0 {
+		notifyCleared(ls, r, c.values.toMap())
+	}
+	if reuse {
+		// Deferred functions may panic: recycle first.
+		recycle(s)
+	}
+	runDeferred(c.deferred)
 }
 
 // Purge removes request data stored for longer than maxAge, in seconds.
@@ -109,31 +286,75 @@ func clear(r *http.Request) {
 // properly set some request data can be kept forever, consuming an increasing
 // amount of memory. In case this is detected, Purge() must be called
 // periodically until the problem is fixed.
+//
+// Functions registered with Defer for the removed requests are run.
 func Purge(maxAge int) int {
-	mutex.Lock()
-	count := 0
-	if maxAge <= 0 {
-		count = len(data)
-		data = make(map[*http.Request]map[interface{}]interface{})
-		datat = make(map[*http.Request]int64)
-	} else {
-		min := time.Now().Unix() - int64(maxAge)
-		for r := range data {
-			if datat[r] < min {
-				clear(r)
-				count++
-			}
+	b := currentBackend()
+	var expired []*http.Request
+	min := now().UnixNano() - int64(maxAge)*int64(time.Second)
+	b.Range(func(r *http.Request, s *Store) bool {
+		if maxAge <= 0 || s.created < min {
+			expired = append(expired, r)
 		}
+		return true
+	})
+	purged := make([]*Store, 0, len(expired))
+	for _, r := range expired {
+		if s := b.Release(r); s != nil {
+			traceClear(r)
+			observe(OpClear, r, nil, nil)
+			purged = append(purged, s)
+		}
+	}
+	deferred := make([][]func(), 0, len(purged))
+	for _, s := range purged {
+		c := s.clear()
+		if maxAge > 0 {
+			countLeak(&c.values)
+		}
+		deferred = append(deferred, c.deferred)
+	}
+	countMetric(&metricsPurge, len(purged))
+	if maxAge > 0 {
+		countMetric(&metricsLeaks, len(purged))
 	}
-	mutex.Unlock()
-	return count
+	notifyPurged(currentListeners(), len(purged))
+	for _, fns := range deferred {
+		runDeferred(fns)
+	}
+	return len(purged)
 }
 
 // ClearHandler wraps an http.Handler and clears request values at the end
 // of a request lifetime.
+//
+// The cleared store is recycled for later requests, to avoid allocating one
+// per request on busy servers.
+//
+// Requests handed to a hijacked connection with TransferToConn are left
+// alone: they are cleared when the connection is closed. Layers left pushed
+// with PushLayer are popped first.
+//
+// The response writer passed to the handler wraps w to hold the values set
+// with SetW. It is an http.Flusher, http.Hijacker or http.Pusher if w is,
+// and supports http.ResponseController.
 func ClearHandler(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		defer Clear(r)
+		if w != nil {
+			w = newContextWriter(w)
+		}
+		defer ClearRequest(r)
 		h.ServeHTTP(w, r)
 	})
 }
+
+// ClearRequest clears the values of a request at the end of its lifetime,
+// like ClearHandler: requests handed to a connection with TransferToConn are
+// left alone, and layers left pushed are popped first. Middleware of other
+// frameworks use it in place of ClearHandler.
+func ClearRequest(r *http.Request) {
+	if !isDetached(r) {
+		popLayers(r)
+		clearRequest(r, true)
+	}
+}
@@ -1,7 +1,14 @@
 package context
 
 import (
+	"bytes"
+	"errors"
+	"io"
 	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 )
 
@@ -28,11 +35,11 @@ func TestContext(t *testing.T) {
 	// Set()
 	Set(r, key1, "1")
 	assertEqual(Get(r, key1), "1")
-	assertEqual(len(data[r]), 1)
+	assertEqual(storeLen(r), 1)
 
 	Set(r, key2, "2")
 	assertEqual(Get(r, key2), "2")
-	assertEqual(len(data[r]), 2)
+	assertEqual(storeLen(r), 2)
 
 	//GetOk
 	value, ok := GetOk(r, key1)
@@ -71,15 +78,159 @@ func TestContext(t *testing.T) {
 	// Delete()
 	Delete(r, key1)
 	assertEqual(Get(r, key1), nil)
-	assertEqual(len(data[r]), 2)
+	assertEqual(storeLen(r), 2)
 
 	Delete(r, key2)
 	assertEqual(Get(r, key2), nil)
-	assertEqual(len(data[r]), 1)
+	assertEqual(storeLen(r), 1)
 
 	// Clear()
 	Clear(r)
-	assertEqual(len(data), 0)
+	assertEqual(storeCount(), 0)
+}
+
+func TestBodylessRequests(t *testing.T) {
+	nilBody, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	nilBody.Body = nil
+	noBody, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	noBody.Body = http.NoBody
+
+	// Values are keyed by request, so requests without a body work the
+	// same as any other.
+	for _, r := range []*http.Request{nilBody, noBody} {
+		Set(r, key1, "1")
+		if v := Get(r, key1); v != "1" {
+			t.Errorf("Expected 1, got %v", v)
+		}
+		Clear(r)
+	}
+	if nilBody.Body != nil || noBody.Body != http.NoBody {
+		t.Error("Expected request bodies to be left untouched")
+	}
+}
+
+func TestBodyInterfacesPreserved(t *testing.T) {
+	r, _ := http.NewRequest("POST", "http://localhost:8080/", strings.NewReader("body"))
+	r.Body = http.MaxBytesReader(httptest.NewRecorder(), io.NopCloser(strings.NewReader("too long")), 2)
+	body := r.Body
+	Set(r, key1, "1")
+	defer Clear(r)
+
+	// The package never wraps the body, so its optional interfaces and
+	// error types stay available to handlers.
+	if r.Body != body {
+		t.Fatal("Expected the body to be left untouched")
+	}
+	_, err := io.ReadAll(r.Body)
+	var maxErr *http.MaxBytesError
+	if !errors.As(err, &maxErr) {
+		t.Errorf("Expected *http.MaxBytesError, got %v", err)
+	}
+
+	w, _ := http.NewRequest("POST", "http://localhost:8080/", nil)
+	w.Body = io.NopCloser(bytes.NewReader([]byte("body")))
+	Set(w, key1, "1")
+	defer Clear(w)
+	if _, ok := w.Body.(io.WriterTo); !ok {
+		t.Error("Expected the body to still implement io.WriterTo")
+	}
+}
+
+func TestBodyReplacement(t *testing.T) {
+	r, _ := http.NewRequest("POST", "http://localhost:8080/", strings.NewReader("body"))
+	Set(r, key1, "1")
+	defer Clear(r)
+
+	// Middleware such as decompression or body limits replace the body;
+	// values are bound to the request, not to its body.
+	r.Body = http.MaxBytesReader(httptest.NewRecorder(), r.Body, 1024)
+	r.Body = io.NopCloser(r.Body)
+	if v := Get(r, key1); v != "1" {
+		t.Errorf("Expected 1 after replacing the body, got %v", v)
+	}
+}
+
+func TestGetBodyPreserved(t *testing.T) {
+	r, _ := http.NewRequest("POST", "http://localhost:8080/", strings.NewReader("body"))
+	Set(r, key1, "1")
+	defer Clear(r)
+
+	// Client redirects and retries rely on GetBody, which is left alone.
+	if r.GetBody == nil {
+		t.Fatal("Expected GetBody to be set")
+	}
+	body, err := r.GetBody()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := io.ReadAll(body); string(b) != "body" {
+		t.Errorf("Expected body, got %q", b)
+	}
+}
+
+func TestConcurrentRequestAccess(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+
+	// Handlers may spawn goroutines that share the request.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				Set(r, i, j)
+				Get(r, i)
+				GetAll(r)
+				Delete(r, i)
+			}
+			Set(r, i, i)
+		}(i)
+	}
+	wg.Wait()
+	if n := storeLen(r); n != 8 {
+		t.Errorf("Expected 8 values, got %d", n)
+	}
+}
+
+func TestReadUntouchedRequest(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+
+	// Reading or deleting from a request without values must neither
+	// allocate nor register the request.
+	allocs := testing.AllocsPerRun(100, func() {
+		Get(r, key1)
+		GetOk(r, key1)
+		GetAll(r)
+		Delete(r, key1)
+		Clear(r)
+	})
+	if allocs != 0 {
+		t.Errorf("Expected no allocations, got %v", allocs)
+	}
+	if lookup(r) != nil {
+		t.Error("Expected no store for an untouched request")
+	}
+}
+
+// storeLen returns the number of values stored for a request.
+func storeLen(r *http.Request) int {
+	if s := lookup(r); s != nil {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return s.count()
+	}
+	return 0
+}
+
+// storeCount returns the number of requests with a store.
+func storeCount() int {
+	n := 0
+	currentBackend().Range(func(*http.Request, *Store) bool {
+		n++
+		return true
+	})
+	return n
 }
 
 func parallelReader(r *http.Request, key string, iterations int, wait, done chan struct{}) {
@@ -128,6 +279,20 @@ func benchmarkMutex(b *testing.B, numReaders, numWriters, iterations int) {
 
 }
 
+func BenchmarkRequestLifecycle(b *testing.B) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	h := ClearHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Set(r, key1, "1")
+		Set(r, key2, "2")
+		Get(r, key1)
+		Get(r, key2)
+	}))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		h.ServeHTTP(nil, r)
+	}
+}
+
 func BenchmarkMutexSameReadWrite1(b *testing.B) {
 	benchmarkMutex(b, 1, 1, 32)
 }
@@ -155,3 +320,46 @@ func BenchmarkMutex5(b *testing.B) {
 func BenchmarkMutex6(b *testing.B) {
 	benchmarkMutex(b, 2048, 1024, 512)
 }
+
+func TestPop(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	if _, ok := Pop(r, key1); ok {
+		t.Error("Expected nothing to pop for an unregistered request")
+	}
+	Set(r, key1, "upload.tmp")
+
+	var wg sync.WaitGroup
+	var popped int64
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, ok := Pop(r, key1); ok && v == "upload.tmp" {
+				atomic.AddInt64(&popped, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if popped != 1 {
+		t.Errorf("Expected the value to be popped once, got %d", popped)
+	}
+	if _, ok := GetOk(r, key1); ok {
+		t.Error("Expected the value to be removed")
+	}
+}
+
+func TestHas(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	if Has(r, key1) {
+		t.Error("Expected no value for an unregistered request")
+	}
+	Set(r, key1, nil)
+	if !Has(r, key1) || Has(r, key2) {
+		t.Error("Expected Has to report presence, even of nil values")
+	}
+	if n := testing.AllocsPerRun(10, func() { Has(r, key1) }); n != 0 {
+		t.Errorf("Expected Has not to allocate, got %v", n)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package contextbench provides reproducible benchmarks comparing the
+// backends of gorilla/context under typical workloads, so that users can
+// pick a backend with data for their own machines.
+//
+// Run them from a test file of your own:
+//
+//	func BenchmarkContext(b *testing.B) {
+//		contextbench.BenchmarkAll(b)
+//	}
+//
+// The benchmarks replace the package backend with context.SetBackend while
+// they run, so they must not run in parallel with code using the package.
+package contextbench
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/context"
+)
+
+// Backend is a backend under benchmark.
+type Backend struct {
+	Name string
+	New  func() context.Backend
+}
+
+// Backends lists the backends provided by gorilla/context.
+var Backends = []Backend{
+	{"map", context.NewMapBackend},
+	{"syncmap", context.NewSyncMapBackend},
+	{"sharded", func() context.Backend { return context.NewShardedBackend(0) }},
+	{"body", context.NewBodyBackend},
+}
+
+// Workload describes what each request does during its lifetime.
+type Workload struct {
+	Name string
+	// Keys is the number of distinct keys used by a request.
+	Keys int
+	// Reads and Writes are the number of Get and Set calls per request.
+	Reads, Writes int
+	// Parallelism multiplies GOMAXPROCS to get the number of requests
+	// served concurrently; see testing.B.SetParallelism.
+	Parallelism int
+}
+
+// Workloads lists the workloads run by BenchmarkAll.
+var Workloads = []Workload{
+	{Name: "read-heavy", Keys: 4, Reads: 32, Writes: 4, Parallelism: 4},
+	{Name: "write-heavy", Keys: 4, Reads: 4, Writes: 32, Parallelism: 4},
+	{Name: "mixed", Keys: 4, Reads: 16, Writes: 16, Parallelism: 4},
+	{Name: "many-keys", Keys: 64, Reads: 64, Writes: 64, Parallelism: 4},
+}
+
+// BenchmarkAll runs every workload against every backend as sub-benchmarks
+// named workload/backend.
+func BenchmarkAll(b *testing.B) {
+	for _, w := range Workloads {
+		w := w
+		b.Run(w.Name, func(b *testing.B) {
+			for _, be := range Backends {
+				be := be
+				b.Run(be.Name, func(b *testing.B) {
+					Benchmark(b, be.New(), w)
+				})
+			}
+		})
+	}
+}
+
+// Benchmark runs a workload against a backend. Each iteration serves one
+// request: it sets and gets values as described by the workload, then
+// clears the request.
+func Benchmark(b *testing.B, backend context.Backend, w Workload) {
+	defer context.SetBackend(context.CurrentBackend())
+	context.SetBackend(backend)
+
+	keys := makeKeys(w.Keys)
+	if w.Parallelism > 0 {
+		b.SetParallelism(w.Parallelism)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+		for pb.Next() {
+			Serve(r, keys, w)
+		}
+	})
+}
+
+// Serve runs the workload for a single request, using the given keys.
+func Serve(r *http.Request, keys []interface{}, w Workload) {
+	for i := 0; i < w.Writes; i++ {
+		context.Set(r, keys[i%len(keys)], i)
+	}
+	for i := 0; i < w.Reads; i++ {
+		context.Get(r, keys[i%len(keys)])
+	}
+	context.Clear(r)
+}
+
+// makeKeys returns n keys, converted to interface{} in advance so that the
+// benchmarks don't measure it.
+func makeKeys(n int) []interface{} {
+	if n <= 0 {
+		n = 1
+	}
+	keys := make([]interface{}, n)
+	for i := range keys {
+		keys[i] = key(i)
+	}
+	return keys
+}
+
+type key int
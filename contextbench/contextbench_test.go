@@ -0,0 +1,36 @@
+package contextbench
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/context"
+)
+
+func TestServe(t *testing.T) {
+	defer context.SetBackend(context.CurrentBackend())
+	for _, be := range Backends {
+		context.SetBackend(be.New())
+		for _, w := range Workloads {
+			r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+			Serve(r, makeKeys(w.Keys), w)
+			if _, ok := context.GetAllOk(r); ok {
+				t.Errorf("%s/%s: expected the request to be cleared", w.Name, be.Name)
+			}
+		}
+	}
+}
+
+func BenchmarkBackends(b *testing.B) {
+	BenchmarkAll(b)
+}
+
+func TestBenchmarkRestoresBackend(t *testing.T) {
+	prev := context.CurrentBackend()
+	testing.Benchmark(func(b *testing.B) {
+		Benchmark(b, Backends[len(Backends)-1].New(), Workloads[0])
+	})
+	if context.CurrentBackend() != prev {
+		t.Error("Expected Benchmark to restore the previous backend")
+	}
+}
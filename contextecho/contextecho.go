@@ -0,0 +1,58 @@
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package contextecho runs middleware written against gorilla/context inside
+// echo, keeping the values of the request in sync with those of
+// echo.Context:
+//
+//	e := echo.New()
+//	e.Use(contextecho.Middleware)
+//	e.Use(echo.WrapMiddleware(auth.Middleware))
+//	e.GET("/orders", func(c echo.Context) error {
+//		user := c.Get("user") // stored by auth.Middleware with context.Set
+//		// ...
+//	})
+//
+// Only string keys can be shared, since echo.Context only has those.
+package contextecho
+
+import (
+	"github.com/gorilla/context"
+	"github.com/labstack/echo/v4"
+)
+
+// Middleware clears the values of the request once the handlers of the
+// chain have returned, like context.ClearHandler, and hands them a
+// context whose Set also stores the value for the request, and whose Get
+// falls back to the values of the request. It should come first.
+func Middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		r := c.Request()
+		defer func() {
+			context.ClearRequest(r)
+			if c.Request() != r {
+				// Replaced by a middleware such as echo.WrapMiddleware.
+				context.ClearRequest(c.Request())
+			}
+		}()
+		return next(&syncContext{c})
+	}
+}
+
+// syncContext is the echo.Context passed on by Middleware.
+type syncContext struct {
+	echo.Context
+}
+
+func (c *syncContext) Set(key string, val interface{}) {
+	c.Context.Set(key, val)
+	context.Set(c.Request(), key, val)
+}
+
+func (c *syncContext) Get(key string) interface{} {
+	if v := c.Context.Get(key); v != nil {
+		return v
+	}
+	return context.Get(c.Request(), key)
+}
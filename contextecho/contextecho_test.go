@@ -0,0 +1,86 @@
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package contextecho
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/context"
+	"github.com/labstack/echo/v4"
+)
+
+// auth is middleware written against gorilla/context.
+func auth(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if context.Get(r, "tenant") != "acme" {
+			http.Error(w, "unknown tenant", http.StatusForbidden)
+			return
+		}
+		context.Set(r, "user", "gopher")
+		h.ServeHTTP(w, r)
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	var req *http.Request
+	e := echo.New()
+	e.Use(Middleware, func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req = c.Request()
+			c.Set("tenant", c.QueryParam("tenant"))
+			return next(c)
+		}
+	}, echo.WrapMiddleware(auth))
+	e.GET("/orders", func(c echo.Context) error {
+		if user := c.Get("user"); user != "gopher" {
+			t.Errorf("Expected the user set by the middleware, got %v", user)
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest("GET", "/orders?tenant=acme", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", w.Code)
+	}
+	if _, ok := context.GetAllOk(req); ok {
+		t.Error("Expected the values to be cleared")
+	}
+
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest("GET", "/orders?tenant=other", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403, got %d", w.Code)
+	}
+}
+
+func TestMiddlewareTransferred(t *testing.T) {
+	var (
+		req  *http.Request
+		conn net.Conn
+	)
+	e := echo.New()
+	e.Use(Middleware)
+	e.GET("/ws", func(c echo.Context) error {
+		req = c.Request()
+		context.Set(req, "user", "gopher")
+		client, server := net.Pipe()
+		defer client.Close()
+		conn = context.TransferToConn(req, server)
+		return nil
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ws", nil))
+	if context.Get(req, "user") != "gopher" {
+		t.Error("Expected the values of a transferred request to be kept")
+	}
+	conn.Close()
+	if _, ok := context.GetAllOk(req); ok {
+		t.Error("Expected closing the connection to clear the values")
+	}
+}
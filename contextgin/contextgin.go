@@ -0,0 +1,92 @@
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package contextgin runs middleware written against gorilla/context inside
+// gin, keeping the values of the request in sync with those of gin.Context:
+//
+//	router := gin.New()
+//	router.Use(contextgin.Middleware())
+//	router.Use(contextgin.Wrap(auth.Middleware))
+//	router.GET("/orders", func(c *gin.Context) {
+//		user, _ := c.Get("user") // stored by auth.Middleware with context.Set
+//		// ...
+//	})
+//
+// Only string keys can be shared, since gin.Context only has those.
+package contextgin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/context"
+)
+
+// Middleware clears the values of the request once the handlers of the
+// chain have returned, like context.ClearHandler. It should come first.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		r := c.Request
+		defer func() {
+			context.ClearRequest(r)
+			if c.Request != r {
+				// Replaced by a middleware run with Wrap.
+				context.ClearRequest(c.Request)
+			}
+		}()
+		c.Next()
+	}
+}
+
+// Wrap adapts net/http middleware to gin. The values set with gin.Context.Set
+// are stored for the request before mw runs, and the string keys it stored
+// are set on the gin.Context for the following handlers, and again once it
+// returns. If mw doesn't call the next handler, the chain is aborted.
+func Wrap(mw func(http.Handler) http.Handler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		toStore(c)
+		called := false
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+			if r != c.Request {
+				c.Request = r
+				toStore(c)
+			}
+			fromStore(c)
+			c.Next()
+			toStore(c)
+		})
+		mw(next).ServeHTTP(c.Writer, c.Request)
+		fromStore(c)
+		if !called {
+			c.Abort()
+		}
+	}
+}
+
+// Set stores a value for a given key both for the request and on the
+// gin.Context, for gin handlers calling code that uses gorilla/context.
+func Set(c *gin.Context, key string, val interface{}) {
+	c.Set(key, val)
+	context.Set(c.Request, key, val)
+}
+
+// toStore stores the values of the gin.Context for its request. They are
+// read from a copy: Copy locks c.Keys, which handlers may set from other
+// goroutines.
+func toStore(c *gin.Context) {
+	for k, v := range c.Copy().Keys {
+		context.Set(c.Request, k, v)
+	}
+}
+
+// fromStore sets the values of the request with string keys on the
+// gin.Context.
+func fromStore(c *gin.Context) {
+	for k, v := range context.GetAll(c.Request) {
+		if name, ok := k.(string); ok {
+			c.Set(name, v)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package contextgin
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/context"
+)
+
+// auth is middleware written against gorilla/context.
+func auth(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if context.Get(r, "tenant") != "acme" {
+			http.Error(w, "unknown tenant", http.StatusForbidden)
+			return
+		}
+		context.Set(r, "user", "gopher")
+		h.ServeHTTP(w, r)
+		if context.Get(r, "order") != 1 {
+			panic("expected the order set by the handler")
+		}
+	})
+}
+
+func TestWrap(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var req *http.Request
+	router := gin.New()
+	router.Use(Middleware(), func(c *gin.Context) {
+		req = c.Request
+		c.Set("tenant", c.Query("tenant"))
+	}, Wrap(auth))
+	router.GET("/orders", func(c *gin.Context) {
+		if user, _ := c.Get("user"); user != "gopher" {
+			t.Errorf("Expected the user set by the middleware, got %v", user)
+		}
+		Set(c, "order", 1)
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/orders?tenant=acme", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", w.Code)
+	}
+	if _, ok := context.GetAllOk(req); ok {
+		t.Error("Expected the values to be cleared")
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/orders?tenant=other", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected the chain to be aborted, got %d", w.Code)
+	}
+}
+
+func TestWrapConcurrentSet(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var wg sync.WaitGroup
+	router := gin.New()
+	router.Use(Middleware(), func(c *gin.Context) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				c.Set(strconv.Itoa(i), i)
+			}
+		}()
+		// Run with -race: Wrap must read the keys under the lock of c.
+		c.Next()
+		wg.Wait()
+	}, Wrap(func(h http.Handler) http.Handler { return h }))
+	router.GET("/", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
+
+func TestMiddlewareTransferred(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var (
+		req  *http.Request
+		conn net.Conn
+	)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/ws", func(c *gin.Context) {
+		req = c.Request
+		context.Set(req, "user", "gopher")
+		client, server := net.Pipe()
+		defer client.Close()
+		conn = context.TransferToConn(req, server)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ws", nil))
+	if context.Get(req, "user") != "gopher" {
+		t.Error("Expected the values of a transferred request to be kept")
+	}
+	conn.Close()
+	if _, ok := context.GetAllOk(req); ok {
+		t.Error("Expected closing the connection to clear the values")
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package contextgrpc bridges gorilla/context with gRPC metadata.
+//
+// HTTP gateways calling gRPC backends use OutgoingContext to send request
+// values as metadata:
+//
+//	ctx := contextgrpc.OutgoingContext(r.Context(), r,
+//		contextgrpc.Mapping{Key: tenantKey, Name: "tenant"})
+//	resp, err := client.Call(ctx, req)
+//
+// gRPC servers use the interceptors to get a request carrier, found with
+// Request, holding the incoming metadata, so that code written for
+// gorilla/context works for gRPC calls too:
+//
+//	s := grpc.NewServer(grpc.UnaryInterceptor(contextgrpc.UnaryServerInterceptor(
+//		contextgrpc.Mapping{Key: tenantKey, Name: "tenant"})))
+//
+//	// In a method:
+//	tenant := context.Get(contextgrpc.Request(ctx), tenantKey)
+package contextgrpc
+
+import (
+	stdcontext "context"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Mapping names the metadata key a request value is sent as.
+type Mapping struct {
+	Key interface{}
+	// Name is the metadata key. gRPC lowercases it.
+	Name string
+}
+
+// Metadata returns the values stored for r under the keys of mappings as
+// metadata, formatted with fmt.Sprint. Values that are not stored are
+// skipped.
+func Metadata(r *http.Request, mappings ...Mapping) metadata.MD {
+	md := metadata.MD{}
+	for _, m := range mappings {
+		if v, ok := context.GetOk(r, m.Key); ok {
+			md.Set(m.Name, fmt.Sprint(v))
+		}
+	}
+	return md
+}
+
+// OutgoingContext returns ctx with the metadata returned by Metadata added
+// to its outgoing metadata.
+func OutgoingContext(ctx stdcontext.Context, r *http.Request, mappings ...Mapping) stdcontext.Context {
+	md := Metadata(r, mappings...)
+	if len(md) == 0 {
+		return ctx
+	}
+	if old, ok := metadata.FromOutgoingContext(ctx); ok {
+		md = metadata.Join(old, md)
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// carrierKey is the context key of the request carriers.
+type carrierKey struct{}
+
+// Request returns the request carrier of a gRPC call handled by one of the
+// interceptors, or nil. Carriers only hold values: their other fields are
+// empty, except Header which holds the incoming metadata.
+func Request(ctx stdcontext.Context) *http.Request {
+	r, _ := ctx.Value(carrierKey{}).(*http.Request)
+	return r
+}
+
+// carry returns ctx with a new request carrier, storing the incoming
+// metadata named by mappings under their keys. The first value of every
+// name is used. The carrier must be cleared once the call is done.
+func carry(ctx stdcontext.Context, mappings []Mapping) stdcontext.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	r := &http.Request{Header: http.Header{}}
+	for name, values := range md {
+		r.Header[http.CanonicalHeaderKey(name)] = values
+	}
+	for _, m := range mappings {
+		if v := md.Get(m.Name); len(v) > 0 {
+			context.Set(r, m.Key, v[0])
+		}
+	}
+	return stdcontext.WithValue(ctx, carrierKey{}, r)
+}
+
+// UnaryServerInterceptor returns an interceptor giving every unary call a
+// request carrier holding the incoming metadata named by mappings.
+func UnaryServerInterceptor(mappings ...Mapping) grpc.UnaryServerInterceptor {
+	return func(ctx stdcontext.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx = carry(ctx, mappings)
+		defer context.Clear(Request(ctx))
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns an interceptor giving every streaming call
+// a request carrier holding the incoming metadata named by mappings.
+func StreamServerInterceptor(mappings ...Mapping) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := carry(ss.Context(), mappings)
+		defer context.Clear(Request(ctx))
+		return handler(srv, &stream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// stream replaces the context of a server stream.
+type stream struct {
+	grpc.ServerStream
+	ctx stdcontext.Context
+}
+
+func (s *stream) Context() stdcontext.Context {
+	return s.ctx
+}
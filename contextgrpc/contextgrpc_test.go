@@ -0,0 +1,83 @@
+package contextgrpc
+
+import (
+	stdcontext "context"
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type key int
+
+const (
+	tenantKey key = iota
+	userKey
+)
+
+var mappings = []Mapping{{tenantKey, "tenant"}, {userKey, "user"}}
+
+func TestOutgoingContext(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer context.Clear(r)
+	context.Set(r, tenantKey, 42)
+
+	ctx := metadata.AppendToOutgoingContext(stdcontext.Background(), "other", "x")
+	md, _ := metadata.FromOutgoingContext(OutgoingContext(ctx, r, mappings...))
+	if v := md.Get("tenant"); len(v) != 1 || v[0] != "42" {
+		t.Errorf("Expected the tenant in the metadata, got %v", md)
+	}
+	if len(md.Get("user")) != 0 || len(md.Get("other")) != 1 {
+		t.Errorf("Unexpected metadata %v", md)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	ctx := metadata.NewIncomingContext(stdcontext.Background(), metadata.Pairs("tenant", "42", "x-other", "y"))
+	var carrier *http.Request
+	_, err := UnaryServerInterceptor(mappings...)(ctx, nil, &grpc.UnaryServerInfo{},
+		func(ctx stdcontext.Context, req interface{}) (interface{}, error) {
+			carrier = Request(ctx)
+			if v := context.Get(carrier, tenantKey); v != "42" {
+				t.Errorf("Expected the tenant, got %v", v)
+			}
+			if _, ok := context.GetOk(carrier, userKey); ok {
+				t.Error("Expected no user")
+			}
+			if v := carrier.Header.Get("X-Other"); v != "y" {
+				t.Errorf("Expected the metadata in the header, got %q", v)
+			}
+			return nil, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := context.GetAllOk(carrier); ok {
+		t.Error("Expected the carrier to be cleared")
+	}
+}
+
+type fakeStream struct {
+	grpc.ServerStream
+	ctx stdcontext.Context
+}
+
+func (s fakeStream) Context() stdcontext.Context {
+	return s.ctx
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	ctx := metadata.NewIncomingContext(stdcontext.Background(), metadata.Pairs("user", "gopher"))
+	err := StreamServerInterceptor(mappings...)(nil, fakeStream{ctx: ctx}, &grpc.StreamServerInfo{},
+		func(srv interface{}, ss grpc.ServerStream) error {
+			if v := context.Get(Request(ss.Context()), userKey); v != "gopher" {
+				t.Errorf("Expected the user, got %v", v)
+			}
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package contextjwt verifies bearer tokens and stores their claims with
+// gorilla/context:
+//
+//	h = contextjwt.Handler(h, contextjwt.HS256(secret))
+//
+//	// In a handler:
+//	if contextjwt.Subject(r) == "" {
+//		http.Error(w, "login required", http.StatusUnauthorized)
+//		return
+//	}
+//
+// Verification is pluggable: HS256 covers shared secrets, and a JWT library
+// can be plugged in with VerifierFunc for other algorithms.
+package contextjwt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/context"
+)
+
+// Verifier verifies a token and returns its claims.
+type Verifier interface {
+	Verify(token string) (map[string]interface{}, error)
+}
+
+// VerifierFunc adapts a function to Verifier.
+type VerifierFunc func(token string) (map[string]interface{}, error)
+
+// Verify calls f.
+func (f VerifierFunc) Verify(token string) (map[string]interface{}, error) {
+	return f(token)
+}
+
+// Errors returned by the verifier of HS256.
+var (
+	ErrMalformed = errors.New("contextjwt: malformed token")
+	ErrSignature = errors.New("contextjwt: invalid signature")
+	ErrExpired   = errors.New("contextjwt: token expired or not valid yet")
+)
+
+type key int
+
+const claimsKey key = 0
+
+func init() {
+	context.AllowKey(claimsKey)
+}
+
+// Handler wraps h to verify the bearer token of the Authorization header of
+// every request and store its claims, found with Claims and Subject. The
+// subject is also stored as the user of the request, with context.SetUser.
+// Requests with an invalid token are answered with 401 Unauthorized.
+// Requests without a token are passed to h without claims.
+func Handler(h http.Handler, v Verifier) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearer(r)
+		if !ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+		claims, err := v.Verify(token)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		context.Set(r, claimsKey, claims)
+		if sub, ok := claims["sub"].(string); ok && sub != "" {
+			context.SetUser(r, sub)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// bearer returns the bearer token of a request.
+func bearer(r *http.Request) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(auth[7:])
+	return token, token != ""
+}
+
+// Claims returns the claims stored by Handler, or nil.
+func Claims(r *http.Request) map[string]interface{} {
+	claims, _ := context.Get(r, claimsKey).(map[string]interface{})
+	return claims
+}
+
+// Subject returns the "sub" claim stored by Handler, or "".
+func Subject(r *http.Request) string {
+	sub, _ := Claims(r)["sub"].(string)
+	return sub
+}
+
+// HS256 returns a verifier of tokens signed with HMAC-SHA256 and secret. It
+// checks the "exp" and "nbf" claims if present, against the clock of
+// context.SetClock, and rejects tokens where they are not numbers.
+func HS256(secret []byte) Verifier {
+	return VerifierFunc(func(token string) (map[string]interface{}, error) {
+		parts := strings.Split(token, ".")
+		if len(parts) != 3 {
+			return nil, ErrMalformed
+		}
+		var header struct {
+			Alg string `json:"alg"`
+		}
+		if err := decode(parts[0], &header); err != nil {
+			return nil, err
+		}
+		if header.Alg != "HS256" {
+			return nil, ErrSignature
+		}
+		sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+		if err != nil {
+			return nil, ErrMalformed
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(parts[0] + "." + parts[1]))
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return nil, ErrSignature
+		}
+		var claims map[string]interface{}
+		if err := decode(parts[1], &claims); err != nil {
+			return nil, err
+		}
+		now := float64(context.Now().Unix())
+		exp, err := numericDate(claims, "exp")
+		if err != nil {
+			return nil, err
+		}
+		nbf, err := numericDate(claims, "nbf")
+		if err != nil {
+			return nil, err
+		}
+		if (exp != nil && now >= *exp) || (nbf != nil && now < *nbf) {
+			return nil, ErrExpired
+		}
+		return claims, nil
+	})
+}
+
+// numericDate returns the date of a claim, nil if it is missing, or
+// ErrMalformed if it is not a number.
+func numericDate(claims map[string]interface{}, name string) (*float64, error) {
+	v, ok := claims[name]
+	if !ok {
+		return nil, nil
+	}
+	date, ok := v.(float64)
+	if !ok {
+		return nil, ErrMalformed
+	}
+	return &date, nil
+}
+
+func decode(part string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return ErrMalformed
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return ErrMalformed
+	}
+	return nil
+}
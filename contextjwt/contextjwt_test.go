@@ -0,0 +1,107 @@
+package contextjwt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/context"
+)
+
+var secret = []byte("secret")
+
+func sign(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+	enc := func(v interface{}) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	s := enc(map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + enc(claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(s))
+	return s + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func serve(auth string) (subject string, claims map[string]interface{}, code int) {
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subject, claims = Subject(r), Claims(r)
+		// A user other than the subject fails the subject checks.
+		if u, _ := context.User(r).(string); u != subject {
+			subject = "user " + u
+		}
+	}), HS256(secret))
+	r := httptest.NewRequest("GET", "/", nil)
+	if auth != "" {
+		r.Header.Set("Authorization", auth)
+	}
+	w := httptest.NewRecorder()
+	context.ClearHandler(h).ServeHTTP(w, r)
+	return subject, claims, w.Code
+}
+
+func TestHandler(t *testing.T) {
+	token := sign(t, map[string]interface{}{"sub": "gopher", "exp": time.Now().Add(time.Hour).Unix()})
+	if sub, claims, code := serve("Bearer " + token); sub != "gopher" || claims["exp"] == nil || code != 200 {
+		t.Errorf("Unexpected result %q %v %d", sub, claims, code)
+	}
+	if sub, claims, code := serve(""); sub != "" || claims != nil || code != 200 {
+		t.Errorf("Expected anonymous requests to pass, got %q %v %d", sub, claims, code)
+	}
+
+	expired := sign(t, map[string]interface{}{"sub": "gopher", "exp": time.Now().Add(-time.Hour).Unix()})
+	for _, auth := range []string{"Bearer " + expired, "Bearer " + token + "x", "bearer a.b", "Bearer x.y.z"} {
+		if _, _, code := serve(auth); code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 for %q, got %d", auth, code)
+		}
+	}
+}
+
+func TestVerifierFunc(t *testing.T) {
+	v := VerifierFunc(func(token string) (map[string]interface{}, error) {
+		return map[string]interface{}{"sub": token}, nil
+	})
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if Subject(r) != "opaque" {
+			t.Errorf("Unexpected subject %q", Subject(r))
+		}
+	}), v)
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer opaque")
+	context.ClearHandler(h).ServeHTTP(httptest.NewRecorder(), r)
+}
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+func TestHS256Claims(t *testing.T) {
+	v := HS256(secret)
+	if _, err := v.Verify(sign(t, map[string]interface{}{"exp": "tomorrow"})); err != ErrMalformed {
+		t.Errorf("Expected ErrMalformed for a string exp, got %v", err)
+	}
+	if _, err := v.Verify(sign(t, map[string]interface{}{"nbf": true})); err != ErrMalformed {
+		t.Errorf("Expected ErrMalformed for a bool nbf, got %v", err)
+	}
+
+	exp := time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC)
+	token := sign(t, map[string]interface{}{"exp": exp.Unix()})
+	defer context.SetClock(nil)
+	context.SetClock(fixedClock(exp.Add(-time.Hour)))
+	if _, err := v.Verify(token); err != nil {
+		t.Errorf("Expected the token to be valid before exp, got %v", err)
+	}
+	context.SetClock(fixedClock(exp))
+	if _, err := v.Verify(token); err != ErrExpired {
+		t.Errorf("Expected ErrExpired at exp, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package contextlocale negotiates the language of every request and stores
+// it with gorilla/context:
+//
+//	h = contextlocale.Handler(h, []language.Tag{language.English, language.French},
+//		contextlocale.WithQuery("lang"), contextlocale.WithCookie("lang"))
+//
+//	// In a handler:
+//	printer := message.NewPrinter(contextlocale.Locale(r))
+package contextlocale
+
+import (
+	"net/http"
+
+	"github.com/gorilla/context"
+	"golang.org/x/text/language"
+)
+
+type key int
+
+const localeKey key = 0
+
+func init() {
+	context.AllowKey(localeKey)
+}
+
+// Option configures Handler.
+type Option func(*options)
+
+type options struct {
+	query  string
+	cookie string
+}
+
+// WithQuery lets the query parameter name override the Accept-Language
+// header.
+func WithQuery(name string) Option {
+	return func(o *options) {
+		o.query = name
+	}
+}
+
+// WithCookie lets the cookie name override the Accept-Language header. The
+// query parameter, if any, takes precedence.
+func WithCookie(name string) Option {
+	return func(o *options) {
+		o.cookie = name
+	}
+}
+
+// Handler wraps h to store the supported language closest to the one
+// requested, found with Locale. The first supported language is the
+// default. The query parameter and cookie set by the options come first,
+// then the Accept-Language header.
+func Handler(h http.Handler, supported []language.Tag, opts ...Option) http.Handler {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	matcher := language.NewMatcher(supported)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var prefs []string
+		if o.query != "" {
+			if v := r.URL.Query().Get(o.query); v != "" {
+				prefs = append(prefs, v)
+			}
+		}
+		if o.cookie != "" {
+			if c, err := r.Cookie(o.cookie); err == nil && c.Value != "" {
+				prefs = append(prefs, c.Value)
+			}
+		}
+		prefs = append(prefs, r.Header.Values("Accept-Language")...)
+		tag, _ := language.MatchStrings(matcher, prefs...)
+		context.Set(r, localeKey, tag)
+		h.ServeHTTP(w, r)
+	})
+}
+
+// Locale returns the language stored by Handler, or language.Und.
+func Locale(r *http.Request) language.Tag {
+	if tag, ok := context.Get(r, localeKey).(language.Tag); ok {
+		return tag
+	}
+	return language.Und
+}
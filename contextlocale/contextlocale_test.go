@@ -0,0 +1,51 @@
+package contextlocale
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/context"
+	"golang.org/x/text/language"
+)
+
+func TestHandler(t *testing.T) {
+	supported := []language.Tag{language.English, language.French, language.German}
+	var got language.Tag
+	h := context.ClearHandler(Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = Locale(r)
+	}), supported, WithQuery("lang"), WithCookie("lang")))
+
+	tests := []struct {
+		url, cookie, header string
+		want                language.Tag
+	}{
+		{"/", "", "", language.English},
+		{"/", "", "fr-CH, de;q=0.8", language.French},
+		{"/", "de", "fr", language.German},
+		{"/?lang=fr", "de", "en", language.French},
+		{"/?lang=xx", "", "de", language.German},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", tt.url, nil)
+		if tt.cookie != "" {
+			r.AddCookie(&http.Cookie{Name: "lang", Value: tt.cookie})
+		}
+		if tt.header != "" {
+			r.Header.Set("Accept-Language", tt.header)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		if base, _ := got.Base(); base != mustBase(tt.want) {
+			t.Errorf("%+v: expected %v, got %v", tt, tt.want, got)
+		}
+	}
+
+	if Locale(httptest.NewRequest("GET", "/", nil)) != language.Und {
+		t.Error("Expected language.Und without Handler")
+	}
+}
+
+func mustBase(tag language.Tag) language.Base {
+	b, _ := tag.Base()
+	return b
+}
@@ -0,0 +1,10 @@
+module github.com/gorilla/context/contextlocale
+
+go 1.20
+
+replace github.com/gorilla/context => ../
+
+require (
+	github.com/gorilla/context v0.0.0
+	golang.org/x/text v0.14.0
+)
@@ -0,0 +1,132 @@
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package contextmux stores the route data of gorilla/mux with
+// gorilla/context, so that route variables and application values are read
+// the same way:
+//
+//	router := mux.NewRouter()
+//	router.Use(contextmux.Middleware)
+//	router.HandleFunc("/users/{id}", showUser).Name("user")
+//	http.ListenAndServe(addr, context.ClearHandler(contextmux.Handler(router)))
+//
+//	func showUser(w http.ResponseWriter, r *http.Request) {
+//		id := contextmux.PathVar(r, "id")
+//		route := contextmux.RouteName(r)
+//		// ...
+//	}
+package contextmux
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+)
+
+type key int
+
+const (
+	varsKey key = iota
+	routeKey
+)
+
+func init() {
+	context.AllowKey(varsKey, routeKey)
+}
+
+var (
+	// defaultsMu guards defaults, the values set with Defaults.
+	defaultsMu sync.RWMutex
+	defaults   = make(map[*mux.Route]map[interface{}]interface{})
+)
+
+// Defaults declares values that Middleware stores for the requests matched
+// by route, such as the auth scope it requires or its cache policy, to be
+// read by generic middleware further down the chain. Values already stored
+// for the request are kept. It returns the route, for chaining:
+//
+//	contextmux.Defaults(router.HandleFunc("/admin", admin), map[interface{}]interface{}{
+//		scopeKey: "admin",
+//	})
+//
+// Calling Defaults again for a route replaces its values.
+func Defaults(route *mux.Route, values map[interface{}]interface{}) *mux.Route {
+	m := make(map[interface{}]interface{}, len(values))
+	for k, v := range values {
+		m[k] = v
+	}
+	defaultsMu.Lock()
+	defaults[route] = m
+	defaultsMu.Unlock()
+	return route
+}
+
+// Handler wraps a router so that the requests it passes to its handlers
+// share the values of the incoming request. The router hands its handlers
+// copies of the request, carrying the route data in their context, which
+// would otherwise have values of their own: those stored before the router
+// would not be visible, and those stored by the handlers would be lost.
+func Handler(router http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		router.ServeHTTP(w, context.Alias(r))
+	})
+}
+
+// Middleware stores the variables and the route name of the matched route,
+// for PathVar, Vars and RouteName, and its Defaults. It is meant for
+// mux.Router.Use, with the router wrapped with Handler.
+//
+// If the router is not wrapped with Handler, the copy of the request the
+// router passes on has values of its own, which nothing else would clear:
+// Middleware clears them when the handler returns.
+func Middleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if context.RequestFromContext(r.Context()) == nil {
+			defer context.Clear(r)
+		}
+		context.Set(r, varsKey, mux.Vars(r))
+		if route := mux.CurrentRoute(r); route != nil {
+			context.Set(r, routeKey, route.GetName())
+			seed(r, route)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// seed stores the defaults of route that r has no value for.
+func seed(r *http.Request, route *mux.Route) {
+	defaultsMu.RLock()
+	values := defaults[route]
+	defaultsMu.RUnlock()
+	if len(values) == 0 {
+		return
+	}
+	context.WithStore(r, func(s context.MutableStore) {
+		for k, v := range values {
+			if _, ok := s.GetOk(k); !ok {
+				s.Set(k, v)
+			}
+		}
+	})
+}
+
+// Vars returns the route variables stored by Middleware, or nil.
+func Vars(r *http.Request) map[string]string {
+	vars, _ := context.Get(r, varsKey).(map[string]string)
+	return vars
+}
+
+// PathVar returns a route variable stored by Middleware, or "".
+func PathVar(r *http.Request, name string) string {
+	return Vars(r)[name]
+}
+
+// RouteName returns the name of the matched route stored by Middleware, or
+// "" if it has none.
+func RouteName(r *http.Request) string {
+	name, _ := context.Get(r, routeKey).(string)
+	return name
+}
@@ -0,0 +1,81 @@
+package contextmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+)
+
+func TestMiddleware(t *testing.T) {
+	var id, route, user string
+	router := mux.NewRouter()
+	router.Use(Middleware)
+	router.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, route = PathVar(r, "id"), RouteName(r)
+		user, _ = context.Get(r, "user").(string)
+		context.Set(r, "status", "found")
+	}).Name("user")
+
+	r := httptest.NewRequest("GET", "/users/42", nil)
+	defer context.Clear(r)
+	context.Set(r, "user", "gopher")
+	Handler(router).ServeHTTP(httptest.NewRecorder(), r)
+
+	if id != "42" || route != "user" {
+		t.Errorf("Unexpected route data %q %q", id, route)
+	}
+	if user != "gopher" {
+		t.Errorf("Expected values stored before the router, got %q", user)
+	}
+	if context.Get(r, "status") != "found" || PathVar(r, "id") != "42" {
+		t.Errorf("Expected values stored by the handler, got %v", context.GetAll(r))
+	}
+}
+
+func TestMiddlewareUnwrapped(t *testing.T) {
+	var inner *http.Request
+	router := mux.NewRouter()
+	router.Use(Middleware)
+	router.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if PathVar(r, "id") != "42" {
+			t.Errorf("Expected the route data, got %v", context.GetAll(r))
+		}
+		inner = r
+	})
+
+	r := httptest.NewRequest("GET", "/users/42", nil)
+	context.ClearHandler(router).ServeHTTP(httptest.NewRecorder(), r)
+	if _, ok := context.GetAllOk(inner); ok {
+		t.Error("Expected the routed request to be cleared")
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	var scope, cache interface{}
+	router := mux.NewRouter()
+	router.Use(Middleware)
+	admin := func(w http.ResponseWriter, r *http.Request) {
+		scope, cache = context.Get(r, "scope"), context.Get(r, "cache")
+	}
+	Defaults(router.HandleFunc("/admin", admin), map[interface{}]interface{}{
+		"scope": "admin",
+		"cache": "no-store",
+	})
+	router.HandleFunc("/public", admin)
+
+	h := context.ClearHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		context.Set(r, "cache", "private")
+		Handler(router).ServeHTTP(w, r)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/admin", nil))
+	if scope != "admin" || cache != "private" {
+		t.Errorf("Expected the route defaults under request values, got %v %v", scope, cache)
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/public", nil))
+	if scope != nil {
+		t.Errorf("Expected no defaults for other routes, got %v", scope)
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/gorilla/context/contextmux
+
+go 1.20
+
+replace github.com/gorilla/context => ../
+
+require (
+	github.com/gorilla/context v0.0.0
+	github.com/gorilla/mux v1.8.1
+)
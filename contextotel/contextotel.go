@@ -0,0 +1,117 @@
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package contextotel bridges gorilla/context with OpenTelemetry tracing.
+//
+// Handler stores the active span of every request, found with Span, and
+// records selected request values as span attributes when the request is
+// cleared:
+//
+//	h := contextotel.Handler(mux,
+//		contextotel.Attribute{Key: userKey, Name: "app.user"})
+//	http.Handle("/", otelhttp.NewHandler(context.ClearHandler(h), "server"))
+package contextotel
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/context"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attribute names the span attribute a request value is recorded as.
+type Attribute struct {
+	Key  interface{}
+	Name string
+}
+
+// spanKey is the key the span of a request is stored under.
+type spanKey struct{}
+
+func init() {
+	context.AllowKey(spanKey{})
+}
+
+// mirror is stored under spanKey.
+type mirror struct {
+	span  trace.Span
+	attrs []Attribute
+}
+
+var once sync.Once
+
+// Handler wraps h to store the span active in the context of each request,
+// as set by otelhttp or another instrumentation, and to record the values
+// stored for attrs as attributes of that span when the request is cleared.
+// Values that are not stored are skipped, and values marked with
+// context.MarkSecret are redacted.
+//
+// Requests must be cleared, by Clear or ClearHandler, while the span is
+// still recording.
+func Handler(h http.Handler, attrs ...Attribute) http.Handler {
+	once.Do(func() {
+		context.AddListener(listener{})
+	})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		span := trace.SpanFromContext(r.Context())
+		context.Set(r, spanKey{}, &mirror{span: span, attrs: attrs})
+		h.ServeHTTP(w, r)
+	})
+}
+
+// Span returns the span stored by Handler for a request, or the span of the
+// request context otherwise.
+func Span(r *http.Request) trace.Span {
+	if m, ok := context.Get(r, spanKey{}).(*mirror); ok {
+		return m.span
+	}
+	return trace.SpanFromContext(r.Context())
+}
+
+// listener records the attributes of the requests cleared.
+type listener struct{}
+
+func (listener) OnStoreCreated(r *http.Request) {}
+
+func (listener) OnCleared(r *http.Request, values map[interface{}]interface{}) {
+	m, ok := values[spanKey{}].(*mirror)
+	if !ok || !m.span.IsRecording() {
+		return
+	}
+	kvs := make([]attribute.KeyValue, 0, len(m.attrs))
+	for _, a := range m.attrs {
+		if v, ok := values[a.Key]; ok {
+			kvs = append(kvs, keyValue(a.Name, context.Redact(a.Key, v)))
+		}
+	}
+	m.span.SetAttributes(kvs...)
+}
+
+func (listener) OnPurged(count int) {}
+
+// keyValue converts a value to an attribute, formatting it with fmt.Sprint
+// unless it has a matching attribute type.
+func keyValue(name string, v interface{}) attribute.KeyValue {
+	k := attribute.Key(name)
+	switch v := v.(type) {
+	case string:
+		return k.String(v)
+	case bool:
+		return k.Bool(v)
+	case int:
+		return k.Int(v)
+	case int64:
+		return k.Int64(v)
+	case float64:
+		return k.Float64(v)
+	case []string:
+		return k.StringSlice(v)
+	case fmt.Stringer:
+		return k.String(v.String())
+	}
+	return k.String(fmt.Sprint(v))
+}
@@ -0,0 +1,60 @@
+package contextotel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/context"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type key int
+
+const (
+	userKey key = iota
+	countKey
+	missingKey
+)
+
+func TestHandler(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	var span trace.Span
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		span = Span(r)
+		context.Set(r, userKey, "gopher")
+		context.Set(r, countKey, 3)
+	}), Attribute{userKey, "app.user"}, Attribute{countKey, "app.count"}, Attribute{missingKey, "app.missing"})
+
+	ctx, root := tracer.Start(httptest.NewRequest("GET", "/", nil).Context(), "request")
+	r := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+	context.ClearHandler(h).ServeHTTP(httptest.NewRecorder(), r)
+	root.End()
+
+	if span != root {
+		t.Error("Expected Span to return the request span")
+	}
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	got := map[attribute.Key]attribute.Value{}
+	for _, kv := range spans[0].Attributes() {
+		got[kv.Key] = kv.Value
+	}
+	if len(got) != 2 || got["app.user"].AsString() != "gopher" || got["app.count"].AsInt64() != 3 {
+		t.Errorf("Unexpected attributes %v", spans[0].Attributes())
+	}
+}
+
+func TestSpanWithoutHandler(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	if Span(r).SpanContext().IsValid() {
+		t.Error("Expected a no-op span")
+	}
+}
@@ -0,0 +1,19 @@
+module github.com/gorilla/context/contextotel
+
+go 1.20
+
+replace github.com/gorilla/context => ../
+
+require (
+	github.com/gorilla/context v0.0.0
+	go.opentelemetry.io/otel v1.19.0
+	go.opentelemetry.io/otel/sdk v1.19.0
+	go.opentelemetry.io/otel/trace v1.19.0
+)
+
+require (
+	github.com/go-logr/logr v1.2.4 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	go.opentelemetry.io/otel/metric v1.19.0 // indirect
+	golang.org/x/sys v0.12.0 // indirect
+)
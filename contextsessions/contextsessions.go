@@ -0,0 +1,163 @@
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package contextsessions loads a gorilla/sessions session once per
+// request, and saves it if it changed:
+//
+//	store := sessions.NewCookieStore(key)
+//	h = context.ClearHandler(contextsessions.Handler(h, store, "session"))
+//
+//	// In a handler:
+//	s := contextsessions.Session(r)
+//	s.Values["visits"] = visits + 1
+package contextsessions
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"reflect"
+
+	"github.com/gorilla/context"
+	"github.com/gorilla/sessions"
+)
+
+type key int
+
+const sessionKey key = 0
+
+func init() {
+	context.AllowKey(sessionKey)
+}
+
+// loaded is stored under sessionKey.
+type loaded struct {
+	session *sessions.Session
+	// initial and maxAge are the values and MaxAge option as loaded, to
+	// find out whether the session must be saved.
+	initial map[interface{}]interface{}
+	maxAge  int
+}
+
+// Handler wraps h to load the session with the given name from store for
+// every request, found with Session. The session is saved if its values
+// changed, when h first writes the response, so that the session cookie can
+// still be set, or when h returns.
+//
+// Errors loading the session, such as an invalid cookie, give a new session,
+// like sessions.Store.Get. Errors saving the session are reported by
+// http.Error if nothing was written yet: the response of h is then replaced,
+// and its writes fail with the error.
+func Handler(h http.Handler, store sessions.Store, name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, _ := store.Get(r, name)
+		l := &loaded{session: s, initial: copyValues(s.Values), maxAge: maxAge(s)}
+		context.Set(r, sessionKey, l)
+		sw := &saveWriter{ResponseWriter: w, r: r, l: l}
+		h.ServeHTTP(sw, r)
+		if err := sw.save(); err != nil && !sw.wrote {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// Session returns the session loaded by Handler, or nil.
+func Session(r *http.Request) *sessions.Session {
+	if l, ok := context.Get(r, sessionKey).(*loaded); ok {
+		return l.session
+	}
+	return nil
+}
+
+func copyValues(m map[interface{}]interface{}) map[interface{}]interface{} {
+	c := make(map[interface{}]interface{}, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+func maxAge(s *sessions.Session) int {
+	if s.Options == nil {
+		return 0
+	}
+	return s.Options.MaxAge
+}
+
+// saveWriter saves the session before the response is first written.
+type saveWriter struct {
+	http.ResponseWriter
+	r     *http.Request
+	l     *loaded
+	saved bool
+	wrote bool
+	// err is the error saving the session before the response was written,
+	// reported instead of the response.
+	err error
+}
+
+// save saves the session if it changed, until it succeeds.
+func (w *saveWriter) save() error {
+	if w.saved {
+		return nil
+	}
+	// Changing MaxAge is how sessions are deleted.
+	if reflect.DeepEqual(w.l.initial, w.l.session.Values) && maxAge(w.l.session) == w.l.maxAge {
+		w.saved = true
+		return nil
+	}
+	if err := w.l.session.Save(w.r, w.ResponseWriter); err != nil {
+		return err
+	}
+	w.saved = true
+	return nil
+}
+
+// begin saves the session before the response is first written, and reports
+// whether the response of h can be written.
+func (w *saveWriter) begin() bool {
+	if !w.wrote {
+		w.wrote = true
+		if w.err = w.save(); w.err != nil {
+			http.Error(w.ResponseWriter, w.err.Error(), http.StatusInternalServerError)
+		}
+	}
+	return w.err == nil
+}
+
+func (w *saveWriter) WriteHeader(code int) {
+	if w.begin() {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *saveWriter) Write(b []byte) (int, error) {
+	if !w.begin() {
+		return 0, w.err
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher, saving the session first.
+func (w *saveWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok && w.begin() {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker. The session is not saved once the
+// connection is hijacked.
+func (w *saveWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	w.saved, w.wrote = true, true
+	return h.Hijack()
+}
+
+// Unwrap returns the original writer, for http.ResponseController.
+func (w *saveWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
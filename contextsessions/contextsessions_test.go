@@ -0,0 +1,89 @@
+package contextsessions
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/context"
+	"github.com/gorilla/sessions"
+)
+
+func TestHandler(t *testing.T) {
+	store := sessions.NewCookieStore([]byte("0123456789abcdef0123456789abcdef"))
+	h := context.ClearHandler(Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := Session(r)
+		if Session(r) != s {
+			t.Error("Expected the same session for the whole request")
+		}
+		if r.URL.Path == "/visit" {
+			n, _ := s.Values["visits"].(int)
+			s.Values["visits"] = n + 1
+		}
+		io.WriteString(w, "ok")
+	}), store, "session"))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/visit", nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("Expected the changed session to be saved, got %v", w.Header())
+	}
+
+	r := httptest.NewRequest("GET", "/read", nil)
+	r.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	var visits interface{}
+	context.ClearHandler(Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		visits = Session(r).Values["visits"]
+	}), store, "session")).ServeHTTP(w, r)
+	if visits != 1 {
+		t.Errorf("Expected 1 visit, got %v", visits)
+	}
+	if len(w.Result().Cookies()) != 0 {
+		t.Error("Expected an unchanged session not to be saved")
+	}
+}
+
+func TestSessionWithoutHandler(t *testing.T) {
+	if Session(httptest.NewRequest("GET", "/", nil)) != nil {
+		t.Error("Expected no session")
+	}
+}
+
+// failingStore is a sessions.Store whose sessions can't be saved.
+type failingStore struct{}
+
+func (f failingStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return f.New(r, name)
+}
+
+func (f failingStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.NewSession(f, name), nil
+}
+
+func (failingStore) Save(r *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	return errors.New("store unavailable")
+}
+
+func TestHandlerSaveError(t *testing.T) {
+	store := failingStore{}
+	var werr error
+	h := context.ClearHandler(Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Session(r).Values["visits"] = 1
+		w.(http.Flusher).Flush()
+		_, werr = io.WriteString(w, "ok")
+	}), store, "session"))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "store unavailable") {
+		t.Errorf("Expected the save error to be reported, got %d %q", w.Code, w.Body.String())
+	}
+	if werr == nil {
+		t.Error("Expected writes after a save error to fail")
+	}
+}
@@ -0,0 +1,12 @@
+module github.com/gorilla/context/contextsessions
+
+go 1.20
+
+replace github.com/gorilla/context => ../
+
+require (
+	github.com/gorilla/context v0.0.0
+	github.com/gorilla/sessions v1.2.2
+)
+
+require github.com/gorilla/securecookie v1.1.2 // indirect
@@ -0,0 +1,65 @@
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package contexttest
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/context"
+)
+
+// AssertSet reports an error unless the value stored for key is deeply
+// equal to want. It returns whether the assertion held.
+func AssertSet(t testing.TB, r *http.Request, key, want interface{}) bool {
+	t.Helper()
+	got, ok := context.GetOk(r, key)
+	switch {
+	case !ok:
+		t.Errorf("no value stored for %s, want %s\nstored values:\n%s", describe(key), describe(want), values(r))
+		return false
+	case !reflect.DeepEqual(got, want):
+		t.Errorf("value stored for %s:\n got: %s\nwant: %s", describe(key), describe(got), describe(want))
+		return false
+	}
+	return true
+}
+
+// AssertAbsent reports an error if a value is stored for key. It returns
+// whether the assertion held.
+func AssertAbsent(t testing.TB, r *http.Request, key interface{}) bool {
+	t.Helper()
+	if got, ok := context.GetOk(r, key); ok {
+		t.Errorf("unexpected value stored for %s: %s", describe(key), describe(got))
+		return false
+	}
+	return true
+}
+
+// AssertCleared reports an error unless the request was cleared, or never
+// held values. It returns whether the assertion held.
+func AssertCleared(t testing.TB, r *http.Request) bool {
+	t.Helper()
+	if _, ok := context.GetAllOk(r); ok {
+		t.Errorf("request %s %s was not cleared\nstored values:\n%s", r.Method, r.URL, values(r))
+		return false
+	}
+	return true
+}
+
+// describe formats a key or value with its type.
+func describe(v interface{}) string {
+	return fmt.Sprintf("%#v (%T)", v, v)
+}
+
+// values describes the values stored for r.
+func values(r *http.Request) string {
+	if d := context.Dump(r); d != "" {
+		return d
+	}
+	return "(none)"
+}
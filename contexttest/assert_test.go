@@ -0,0 +1,52 @@
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package contexttest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/context"
+)
+
+// recorder records the errors reported by assertions.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertions(t *testing.T) {
+	r := NewRequest("GET", "/", map[interface{}]interface{}{userKey: "gopher"})
+	rec := &recorder{TB: t}
+	if !AssertSet(rec, r, userKey, "gopher") || !AssertAbsent(rec, r, countKey) {
+		t.Errorf("Unexpected failures %q", rec.errors)
+	}
+
+	if AssertSet(rec, r, userKey, "alice") || AssertSet(rec, r, countKey, 1) ||
+		AssertAbsent(rec, r, userKey) || AssertCleared(rec, r) {
+		t.Error("Expected the assertions to fail")
+	}
+	want := []string{
+		"value stored for 0 (contexttest.key):\n got: \"gopher\" (string)\nwant: \"alice\" (string)",
+		"no value stored for 1 (contexttest.key), want 1 (int)\nstored values:\n0 (contexttest.key) = gopher (string)",
+		"unexpected value stored for 0 (contexttest.key): \"gopher\" (string)",
+		"request GET / was not cleared\nstored values:\n0 (contexttest.key) = gopher (string)",
+	}
+	if strings.Join(rec.errors, "\n\n") != strings.Join(want, "\n\n") {
+		t.Errorf("Unexpected errors:\n%s", strings.Join(rec.errors, "\n\n"))
+	}
+
+	context.Clear(r)
+	if !AssertCleared(rec, r) {
+		t.Error("Expected the request to be cleared")
+	}
+}
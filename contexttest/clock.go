@@ -0,0 +1,45 @@
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package contexttest
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/context"
+)
+
+// Clock is a context.Clock that only moves when advanced, so that tests of
+// time-dependent behavior don't sleep.
+type Clock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+// NewClock returns a Clock set to t, and installs it with context.SetClock
+// until the test ends.
+func NewClock(tb testing.TB, t time.Time) *Clock {
+	c := &Clock{t: t}
+	context.SetClock(c)
+	tb.Cleanup(func() {
+		context.SetClock(nil)
+	})
+	return c
+}
+
+// Now returns the time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+// Advance moves the clock forward by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.t = c.t.Add(d)
+	c.mu.Unlock()
+}
@@ -0,0 +1,22 @@
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package contexttest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/context"
+)
+
+func TestClock(t *testing.T) {
+	c := NewClock(t, time.Date(2012, 1, 1, 0, 0, 0, 0, time.UTC))
+	r := NewRequest("GET", "/", map[interface{}]interface{}{userKey: "gopher"})
+	defer context.Clear(r)
+	c.Advance(time.Minute)
+	if d := context.Elapsed(r); d != time.Minute {
+		t.Errorf("Expected a minute elapsed, got %v", d)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package contexttest provides utilities for testing handlers using
+// gorilla/context, without building the middleware chain that would store
+// their values:
+//
+//	r := contexttest.NewRequest("GET", "/", map[interface{}]interface{}{
+//		userKey: "gopher",
+//	})
+//	defer context.Clear(r)
+//	handler.ServeHTTP(httptest.NewRecorder(), r)
+package contexttest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gorilla/context"
+)
+
+// NewRequest returns a new incoming server request, like
+// httptest.NewRequest, holding the given values. It panics if a value is
+// refused by the limits of the package. The request must be cleared once
+// the test is done.
+func NewRequest(method, target string, values map[interface{}]interface{}) *http.Request {
+	return NewRequestWithBody(method, target, nil, values)
+}
+
+// NewRequestWithBody is NewRequest with a request body.
+func NewRequestWithBody(method, target string, body io.Reader, values map[interface{}]interface{}) *http.Request {
+	r := httptest.NewRequest(method, target, body)
+	for k, v := range values {
+		MustSet(r, k, v)
+	}
+	return r
+}
+
+// MustSet stores a value like context.SetE, and panics if it is refused.
+func MustSet(r *http.Request, key, val interface{}) {
+	if err := context.SetE(r, key, val); err != nil {
+		panic(fmt.Sprintf("contexttest: storing %v: %v", key, err))
+	}
+}
+
+// MustGet returns the value stored for a given key, and panics if there is
+// none.
+func MustGet(r *http.Request, key interface{}) interface{} {
+	v, ok := context.GetOk(r, key)
+	if !ok {
+		panic(fmt.Sprintf("contexttest: no value stored for %v", key))
+	}
+	return v
+}
+
+// MustGetString returns the string stored for a given key, and panics if
+// there is none or if it is not a string.
+func MustGetString(r *http.Request, key interface{}) string {
+	v := MustGet(r, key)
+	s, ok := v.(string)
+	if !ok {
+		panic(fmt.Sprintf("contexttest: value stored for %v is a %T, not a string", key, v))
+	}
+	return s
+}
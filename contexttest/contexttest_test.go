@@ -0,0 +1,53 @@
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package contexttest
+
+import (
+	"testing"
+
+	"github.com/gorilla/context"
+)
+
+type key int
+
+const (
+	userKey key = iota
+	countKey
+)
+
+func TestNewRequest(t *testing.T) {
+	r := NewRequest("GET", "/users", map[interface{}]interface{}{
+		userKey:  "gopher",
+		countKey: 2,
+	})
+	defer context.Clear(r)
+	if r.URL.Path != "/users" {
+		t.Errorf("Unexpected path %q", r.URL.Path)
+	}
+	if got := MustGetString(r, userKey); got != "gopher" {
+		t.Errorf("Expected gopher, got %q", got)
+	}
+	if got := MustGet(r, countKey); got != 2 {
+		t.Errorf("Expected 2, got %v", got)
+	}
+}
+
+func TestMustPanics(t *testing.T) {
+	r := NewRequest("GET", "/", map[interface{}]interface{}{countKey: 2})
+	defer context.Clear(r)
+	for name, f := range map[string]func(){
+		"MustGet":       func() { MustGet(r, userKey) },
+		"MustGetString": func() { MustGetString(r, countKey) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected %s to panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package contexttest
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/context"
+)
+
+// leakTracker records where the stores of requests were created.
+type leakTracker struct {
+	mu     sync.Mutex
+	stores map[*http.Request]string
+}
+
+var (
+	trackersMu sync.Mutex
+	trackers   = make(map[*leakTracker]struct{})
+	listenOnce sync.Once
+)
+
+// listener forwards store events to the active trackers.
+type listener struct{}
+
+func (listener) OnStoreCreated(r *http.Request) {
+	trackersMu.Lock()
+	defer trackersMu.Unlock()
+	if len(trackers) == 0 {
+		return
+	}
+	stack := creator()
+	for lt := range trackers {
+		lt.mu.Lock()
+		lt.stores[r] = stack
+		lt.mu.Unlock()
+	}
+}
+
+func (listener) OnCleared(r *http.Request, values map[interface{}]interface{}) {
+	trackersMu.Lock()
+	defer trackersMu.Unlock()
+	for lt := range trackers {
+		lt.mu.Lock()
+		delete(lt.stores, r)
+		lt.mu.Unlock()
+	}
+}
+
+func (listener) OnPurged(count int) {}
+
+// VerifyNoLeaks fails the test if values stored for requests during the
+// test are still stored when it ends, listing them with the stack that
+// stored the first one. It must be called at the start of the test:
+//
+//	func TestHandler(t *testing.T) {
+//		contexttest.VerifyNoLeaks(t)
+//		...
+//	}
+//
+// Requests are cleared by Clear, ClearHandler or Purge.
+func VerifyNoLeaks(t testing.TB) {
+	t.Helper()
+	listenOnce.Do(func() {
+		context.AddListener(listener{})
+	})
+	lt := &leakTracker{stores: make(map[*http.Request]string)}
+	trackersMu.Lock()
+	trackers[lt] = struct{}{}
+	trackersMu.Unlock()
+	t.Cleanup(func() {
+		trackersMu.Lock()
+		delete(trackers, lt)
+		trackersMu.Unlock()
+		lt.mu.Lock()
+		defer lt.mu.Unlock()
+		for r, stack := range lt.stores {
+			// Purged requests are not reported to listeners.
+			if _, ok := context.GetAllOk(r); !ok {
+				continue
+			}
+			t.Errorf("request %s %s was not cleared\nstored values:\n%s\nfirst value stored at:\n%s",
+				r.Method, r.URL, values(r), stack)
+		}
+	})
+}
+
+// creator returns the stack of the code storing a value for a new request,
+// without the frames of the package.
+func creator() string {
+	var pcs [32]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs[:])])
+	var b strings.Builder
+	inside := true
+	for {
+		f, more := frames.Next()
+		if inside && !strings.HasPrefix(f.Function, "github.com/gorilla/context.") {
+			inside = false
+		}
+		if !inside {
+			fmt.Fprintf(&b, "%s\n\t%s:%d\n", f.Function, f.File, f.Line)
+		}
+		if !more {
+			return b.String()
+		}
+	}
+}
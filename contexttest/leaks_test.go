@@ -0,0 +1,51 @@
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package contexttest
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/context"
+)
+
+// cleanupRecorder is a recorder running its cleanup functions on demand,
+// in the order of testing.T.
+type cleanupRecorder struct {
+	recorder
+	cleanups []func()
+}
+
+func (r *cleanupRecorder) Cleanup(f func()) {
+	r.cleanups = append(r.cleanups, f)
+}
+
+func (r *cleanupRecorder) end() {
+	for i := len(r.cleanups) - 1; i >= 0; i-- {
+		r.cleanups[i]()
+	}
+}
+
+func TestVerifyNoLeaks(t *testing.T) {
+	rec := &cleanupRecorder{recorder: recorder{TB: t}}
+	VerifyNoLeaks(rec)
+	cleared := httptest.NewRequest("GET", "/cleared", nil)
+	context.Set(cleared, userKey, "gopher")
+	context.Clear(cleared)
+	leaked := httptest.NewRequest("GET", "/leaked", nil)
+	defer context.Clear(leaked)
+	context.Set(leaked, userKey, "gopher")
+	rec.end()
+
+	if len(rec.errors) != 1 {
+		t.Fatalf("Expected a leak, got %q", rec.errors)
+	}
+	if err := rec.errors[0]; !strings.Contains(err, "request GET /leaked was not cleared") ||
+		!strings.Contains(err, "0 (contexttest.key) = gopher (string)") ||
+		!strings.Contains(err, "contexttest.TestVerifyNoLeaks\n") {
+		t.Errorf("Unexpected error %q", err)
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package contexttest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/context"
+)
+
+// NewServer starts an httptest.Server serving h wrapped with ClearHandler,
+// as in production. The server is closed when the test ends, and the test
+// fails if requests still hold values then, as with VerifyNoLeaks: copies
+// of requests made with WithContext, for instance, are not cleared by
+// ClearHandler.
+func NewServer(t testing.TB, h http.Handler) *httptest.Server {
+	t.Helper()
+	VerifyNoLeaks(t)
+	ts := httptest.NewServer(context.ClearHandler(h))
+	// Cleanups run last-in first-out: the handlers are done before leaks
+	// are checked.
+	t.Cleanup(ts.Close)
+	return ts
+}
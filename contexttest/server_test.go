@@ -0,0 +1,38 @@
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package contexttest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/context"
+)
+
+func TestNewServer(t *testing.T) {
+	rec := &cleanupRecorder{recorder: recorder{TB: t}}
+	var leaked *http.Request
+	ts := NewServer(rec, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		context.Set(r, userKey, "gopher")
+		if r.URL.Path == "/copy" {
+			leaked = r.WithContext(r.Context())
+			context.Set(leaked, userKey, "gopher")
+		}
+	}))
+	for _, path := range []string{"/", "/copy"} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	rec.end()
+	defer context.Clear(leaked)
+
+	if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], "request GET /copy was not cleared") {
+		t.Errorf("Expected the copy to leak, got %q", rec.errors)
+	}
+}
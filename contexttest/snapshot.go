@@ -0,0 +1,116 @@
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package contexttest
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/context"
+)
+
+var update = flag.Bool("contexttest.update", false, "update the golden files of contexttest.Snapshot")
+
+// Snapshot compares the values stored for r with the golden file
+// testdata/<test name>.golden, and fails the test if they differ. Run the
+// tests with -contexttest.update to write the golden files.
+//
+// Values are written one per line, sorted, with their types:
+//
+//	key (type) = value (type)
+//
+// Values are formatted with fmt's %+v verb after following pointers, so
+// that the snapshot doesn't depend on their addresses. Pointers nested in
+// values are printed as addresses: such values should implement
+// fmt.Stringer.
+func Snapshot(t testing.TB, r *http.Request) {
+	t.Helper()
+	got := SnapshotString(r)
+	name := filepath.Join("testdata", strings.ReplaceAll(t.Name(), "/", "_")+".golden")
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(name)
+	if err != nil {
+		t.Errorf("reading golden file: %v (run with -contexttest.update to create it)", err)
+		return
+	}
+	if got != string(want) {
+		t.Errorf("values differ from %s (-want +got):\n%s", name, lineDiff(string(want), got))
+	}
+}
+
+// SnapshotString returns the values stored for r as written by Snapshot.
+func SnapshotString(r *http.Request) string {
+	var lines []string
+	for k, v := range context.GetAll(r) {
+		lines = append(lines, fmt.Sprintf("%s (%T) = %s (%T)", format(k), k, format(v), v))
+	}
+	sort.Strings(lines)
+	var b strings.Builder
+	for _, l := range lines {
+		b.WriteString(l)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// format formats v with %+v, following pointers, unless they implement
+// fmt.Stringer or error.
+func format(v interface{}) string {
+	switch v.(type) {
+	case fmt.Stringer, error:
+		return fmt.Sprint(v)
+	}
+	rv := reflect.ValueOf(v)
+	prefix := ""
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+		prefix += "&"
+	}
+	if !rv.IsValid() || !rv.CanInterface() {
+		return fmt.Sprintf("%+v", v)
+	}
+	return prefix + fmt.Sprintf("%+v", rv.Interface())
+}
+
+// lineDiff returns the lines of want missing from got, prefixed with "-",
+// and the lines of got missing from want, prefixed with "+".
+func lineDiff(want, got string) string {
+	count := make(map[string]int)
+	for _, l := range strings.Split(want, "\n") {
+		count[l]++
+	}
+	for _, l := range strings.Split(got, "\n") {
+		count[l]--
+	}
+	var lines []string
+	for l, n := range count {
+		for ; n > 0; n-- {
+			lines = append(lines, "- "+l)
+		}
+		for ; n < 0; n++ {
+			lines = append(lines, "+ "+l)
+		}
+	}
+	// Sort by line, ignoring the marks.
+	sort.Slice(lines, func(i, j int) bool {
+		return lines[i][2:] < lines[j][2:]
+	})
+	return strings.Join(lines, "\n")
+}
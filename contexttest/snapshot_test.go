@@ -0,0 +1,38 @@
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package contexttest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gorilla/context"
+)
+
+type account struct {
+	ID    int
+	Roles []string
+}
+
+func TestSnapshot(t *testing.T) {
+	r := NewRequest("GET", "/", map[interface{}]interface{}{
+		userKey:   "gopher",
+		countKey:  2,
+		"account": &account{ID: 1, Roles: []string{"admin"}},
+	})
+	defer context.Clear(r)
+	Snapshot(t, r)
+	if *update {
+		return
+	}
+
+	context.Set(r, countKey, 3)
+	rec := &recorder{TB: t}
+	Snapshot(rec, r)
+	if len(rec.errors) != 1 || !strings.HasSuffix(rec.errors[0],
+		"- 1 (contexttest.key) = 2 (int)\n+ 1 (contexttest.key) = 3 (int)") {
+		t.Errorf("Unexpected errors %q", rec.errors)
+	}
+}
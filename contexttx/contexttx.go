@@ -0,0 +1,120 @@
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package contexttx runs every request in a database transaction stored with
+// gorilla/context:
+//
+//	h = contexttx.Handler(h, db, nil)
+//
+//	// In a handler:
+//	_, err := contexttx.Tx(r).Exec("UPDATE accounts SET ...")
+//
+// The transaction is committed once the handler returns, unless it answered
+// with an error status or panicked, in which case it is rolled back.
+package contexttx
+
+import (
+	"bufio"
+	"database/sql"
+	"net"
+	"net/http"
+
+	"github.com/gorilla/context"
+)
+
+type key int
+
+const txKey key = 0
+
+func init() {
+	context.AllowKey(txKey)
+}
+
+// Handler wraps h to begin a transaction on db, with opts, for every request.
+// The transaction is rolled back if h panics or answers with a status code
+// of 400 or more, and committed otherwise.
+//
+// The transaction ends after h returns: a response written by h is sent
+// before the commit, whose failure can then only be reported with a 500
+// Internal Server Error if h wrote nothing.
+func Handler(h http.Handler, db *sql.DB, opts *sql.TxOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tx, err := db.BeginTx(r.Context(), opts)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		context.Set(r, txKey, tx)
+		defer context.Delete(r, txKey)
+		sw := &statusWriter{ResponseWriter: w}
+		defer func() {
+			// Rollback fails if the transaction is already done or the
+			// connection is broken, in which case the server discards the
+			// transaction: there is nothing left to do.
+			if p := recover(); p != nil {
+				_ = tx.Rollback()
+				panic(p)
+			}
+			if sw.status >= http.StatusBadRequest {
+				_ = tx.Rollback()
+				return
+			}
+			if err := tx.Commit(); err != nil && sw.status == 0 {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}()
+		h.ServeHTTP(sw, r)
+	})
+}
+
+// Tx returns the transaction of a request begun by Handler, or nil.
+func Tx(r *http.Request) *sql.Tx {
+	tx, _ := context.Get(r, txKey).(*sql.Tx)
+	return tx
+}
+
+// statusWriter records the status code of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher. Flushing commits to the status written so
+// far, or to http.StatusOK.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker. The transaction of a hijacked request is
+// committed unless an error status was written before.
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,100 @@
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package contexttx
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// fakeDriver counts the transactions that are committed and rolled back.
+type fakeDriver struct {
+	mu                 sync.Mutex
+	commits, rollbacks int
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{d}, nil }
+
+func (d *fakeDriver) counts() (int, int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.commits, d.rollbacks
+}
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)           { return fakeTx(c), nil }
+
+type fakeTx struct{ d *fakeDriver }
+
+func (t fakeTx) Commit() error {
+	t.d.mu.Lock()
+	t.d.commits++
+	t.d.mu.Unlock()
+	return nil
+}
+
+func (t fakeTx) Rollback() error {
+	t.d.mu.Lock()
+	t.d.rollbacks++
+	t.d.mu.Unlock()
+	return nil
+}
+
+func TestHandler(t *testing.T) {
+	d := new(fakeDriver)
+	sql.Register("contexttx-fake", d)
+	db, err := sql.Open("contexttx-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	serve := func(h http.HandlerFunc) {
+		defer func() { recover() }()
+		r := httptest.NewRequest("GET", "/", nil)
+		Handler(h, db, nil).ServeHTTP(httptest.NewRecorder(), r)
+		if Tx(r) != nil {
+			t.Error("Expected the transaction to be removed")
+		}
+	}
+
+	serve(func(w http.ResponseWriter, r *http.Request) {
+		if Tx(r) == nil {
+			t.Error("Expected a transaction")
+		}
+		w.Write([]byte("ok"))
+	})
+	if c, rb := d.counts(); c != 1 || rb != 0 {
+		t.Errorf("Expected a commit, got %d commits and %d rollbacks", c, rb)
+	}
+
+	serve(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "conflict", http.StatusConflict)
+	})
+	serve(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	if c, rb := d.counts(); c != 1 || rb != 2 {
+		t.Errorf("Expected 2 rollbacks, got %d commits and %d rollbacks", c, rb)
+	}
+	serve(func(w http.ResponseWriter, r *http.Request) {
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("Expected the writer to be an http.Flusher")
+		}
+		f.Flush()
+	})
+	if c, rb := d.counts(); c != 2 || rb != 2 {
+		t.Errorf("Expected a flushed response to commit, got %d commits and %d rollbacks", c, rb)
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package contextzap turns gorilla/context values into zap fields:
+//
+//	logger.Info("done", contextzap.ZapFields(r,
+//		contextzap.Field{Key: requestIDKey, Name: "request_id"})...)
+package contextzap
+
+import (
+	"net/http"
+
+	"github.com/gorilla/context"
+	"go.uber.org/zap"
+)
+
+// Field names the zap field a request value is logged as.
+type Field struct {
+	Key  interface{}
+	Name string
+}
+
+// ZapFields returns the values stored for r under the keys of fields as zap
+// fields. Values that are not stored are skipped, and values marked with
+// context.MarkSecret are redacted.
+func ZapFields(r *http.Request, fields ...Field) []zap.Field {
+	zf := make([]zap.Field, 0, len(fields))
+	for _, f := range fields {
+		if v, ok := context.GetOk(r, f.Key); ok {
+			zf = append(zf, zap.Any(f.Name, context.Redact(f.Key, v)))
+		}
+	}
+	return zf
+}
+
+// Logger returns base with the fields of ZapFields, to be used for the
+// whole request.
+func Logger(r *http.Request, base *zap.Logger, fields ...Field) *zap.Logger {
+	return base.With(ZapFields(r, fields...)...)
+}
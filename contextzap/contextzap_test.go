@@ -0,0 +1,31 @@
+package contextzap
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/context"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type key int
+
+const (
+	idKey key = iota
+	userKey
+)
+
+func TestZapFields(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer context.Clear(r)
+	context.Set(r, idKey, "42")
+
+	core, logs := observer.New(zap.InfoLevel)
+	Logger(r, zap.New(core), Field{idKey, "request_id"}, Field{userKey, "user"}).Info("done")
+
+	fields := logs.All()[0].ContextMap()
+	if len(fields) != 1 || fields["request_id"] != "42" {
+		t.Errorf("Unexpected fields %v", fields)
+	}
+}
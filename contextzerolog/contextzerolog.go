@@ -0,0 +1,61 @@
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package contextzerolog adds gorilla/context values to zerolog events.
+//
+// ZerologHook adds them to the events logged with the context of a request
+// that went through context.LogContext:
+//
+//	logger := zerolog.New(os.Stderr).Hook(contextzerolog.ZerologHook(
+//		contextzerolog.Field{Key: requestIDKey, Name: "request_id"}))
+//	http.Handle("/", context.ClearHandler(context.LogContext(mux)))
+//
+//	// In a handler:
+//	logger.Info().Ctx(r.Context()).Msg("done")
+package contextzerolog
+
+import (
+	"net/http"
+
+	"github.com/gorilla/context"
+	"github.com/rs/zerolog"
+)
+
+// Field names the event field a request value is logged as.
+type Field struct {
+	Key  interface{}
+	Name string
+}
+
+// ZerologHook returns a hook adding the values stored under the keys of
+// fields to events whose context belongs to a request, as found by
+// context.RequestFromContext. Values that are not stored are skipped, and
+// values marked with context.MarkSecret are redacted.
+func ZerologHook(fields ...Field) zerolog.Hook {
+	return zerolog.HookFunc(func(e *zerolog.Event, level zerolog.Level, msg string) {
+		if r := context.RequestFromContext(e.GetCtx()); r != nil {
+			add(e, r, fields)
+		}
+	})
+}
+
+// Logger returns base with the values stored for r under the keys of
+// fields, to be used for the whole request.
+func Logger(r *http.Request, base zerolog.Logger, fields ...Field) zerolog.Logger {
+	c := base.With()
+	for _, f := range fields {
+		if v, ok := context.GetOk(r, f.Key); ok {
+			c = c.Interface(f.Name, context.Redact(f.Key, v))
+		}
+	}
+	return c.Logger()
+}
+
+func add(e *zerolog.Event, r *http.Request, fields []Field) {
+	for _, f := range fields {
+		if v, ok := context.GetOk(r, f.Key); ok {
+			e.Interface(f.Name, context.Redact(f.Key, v))
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package contextzerolog
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/context"
+	"github.com/rs/zerolog"
+)
+
+type key int
+
+const (
+	idKey key = iota
+	userKey
+)
+
+func TestZerologHook(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf).Hook(ZerologHook(Field{idKey, "request_id"}, Field{userKey, "user"}))
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer context.Clear(r)
+	context.Set(r, idKey, "42")
+	context.LogContext(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.Info().Ctx(r.Context()).Msg("done")
+	})).ServeHTTP(httptest.NewRecorder(), r)
+
+	if got := strings.TrimSpace(buf.String()); got != `{"level":"info","request_id":"42","message":"done"}` {
+		t.Errorf("Unexpected output %s", got)
+	}
+
+	buf.Reset()
+	logger.Info().Msg("outside")
+	if strings.Contains(buf.String(), "request_id") {
+		t.Errorf("Unexpected output %s", buf.String())
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer context.Clear(r)
+	context.Set(r, idKey, "42")
+
+	logger := Logger(r, zerolog.New(&buf), Field{idKey, "request_id"})
+	logger.Info().Msg("done")
+	if got := strings.TrimSpace(buf.String()); got != `{"level":"info","request_id":"42","message":"done"}` {
+		t.Errorf("Unexpected output %s", got)
+	}
+}
@@ -0,0 +1,55 @@
+package context
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+var copyOnWrite int32
+
+// SetCopyOnWrite enables or disables copy-on-write mode for stores created
+// afterwards.
+//
+// In copy-on-write mode, reads don't take any lock: they use an immutable
+// copy of the values that every write replaces. This suits handlers that
+// share a request among many reading goroutines and rarely write, at the
+// cost of copying all values of a request on every write. Reads in this mode
+// don't count as uses for EvictLRU.
+func SetCopyOnWrite(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&copyOnWrite, v)
+}
+
+// snapshot is an immutable copy of the values of a store.
+type snapshot struct {
+	owner  *http.Request
+	values valueSet
+}
+
+// ownedBy reports whether the snapshot belongs to r, like Store.ownedBy.
+func (snap *snapshot) ownedBy(r *http.Request) bool {
+	return snap.owner == nil || snap.owner == r || snap.owner == aliasOf(r)
+}
+
+// initSnapshot sets up copy-on-write mode if it is enabled. It must be
+// called with the store locked for writing, or before it is shared.
+func (s *Store) initSnapshot() {
+	if atomic.LoadInt32(&copyOnWrite) == 0 {
+		s.snap.Store(nil)
+		return
+	}
+	s.snap.Store(&snapshot{owner: s.owner})
+	s.publish()
+}
+
+// publish replaces the snapshot of a store in copy-on-write mode. It must be
+// called with the store locked for writing, after every change.
+func (s *Store) publish() {
+	if s.holdPublish || s.snap.Load() == nil {
+		return
+	}
+	s.snap.Store(&snapshot{owner: s.owner, values: s.values.clone()})
+}
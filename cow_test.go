@@ -0,0 +1,66 @@
+package context
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestCopyOnWrite(t *testing.T) {
+	SetCopyOnWrite(true)
+	defer SetCopyOnWrite(false)
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+
+	Set(r, key1, "1")
+	if s := lookup(r); s.snap.Load() == nil {
+		t.Fatal("Expected the store to be in copy-on-write mode")
+	}
+	all := GetAll(r)
+	Set(r, key2, "2")
+	if len(all) != 1 {
+		t.Errorf("Expected earlier results to be unaffected, got %v", all)
+	}
+	if v, ok := GetOk(r, key2); !ok || v != "2" {
+		t.Errorf("Expected 2, got %v", v)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				Get(r, key1)
+				GetAll(r)
+			}
+		}()
+	}
+	for j := 0; j < 2*inlineEntries; j++ {
+		Set(r, j, j)
+	}
+	wg.Wait()
+
+	Delete(r, key1)
+	if Get(r, key1) != nil {
+		t.Error("Expected key1 to be deleted")
+	}
+	if n := len(GetAll(r)); n != 2*inlineEntries+1 {
+		t.Errorf("Expected %d values, got %d", 2*inlineEntries+1, n)
+	}
+	Clear(r)
+	if Get(r, key2) != nil {
+		t.Error("Expected values to be cleared")
+	}
+}
+
+func BenchmarkReadMostly(b *testing.B) {
+	benchmarkMutex(b, 16, 2, 64)
+}
+
+func BenchmarkReadMostlyCopyOnWrite(b *testing.B) {
+	SetCopyOnWrite(true)
+	defer SetCopyOnWrite(false)
+	benchmarkMutex(b, 16, 2, 64)
+}
@@ -0,0 +1,154 @@
+package context
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DebugEntry describes a value in the report of DebugHandler.
+type DebugEntry struct {
+	Key  string `json:"key"`
+	Type string `json:"type"`
+}
+
+// DebugStore describes a request in the report of DebugHandler.
+type DebugStore struct {
+	Method string       `json:"method"`
+	URL    string       `json:"url"`
+	Age    string       `json:"age"`
+	Tenant string       `json:"tenant,omitempty"`
+	Values []DebugEntry `json:"values"`
+}
+
+// DebugHandler returns a handler reporting the requests currently holding
+// values, with their method, URL, age, and the keys and types of their
+// values. Values themselves are not shown. This helps finding requests that
+// are never cleared.
+//
+// The report is HTML, or JSON if the request has "format=json" in its query
+// or accepts "application/json". The handler must be protected by the
+// caller, since URLs and keys may be sensitive:
+//
+//	http.Handle("/debug/context", requireAdmin(context.DebugHandler()))
+func DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stores := debugStores()
+		// Render to a buffer, so that failures are answered with an error
+		// instead of a truncated report.
+		var (
+			buf         bytes.Buffer
+			err         error
+			contentType = "text/html; charset=utf-8"
+		)
+		if r.URL.Query().Get("format") == "json" ||
+			strings.Contains(r.Header.Get("Accept"), "application/json") {
+			contentType = "application/json"
+			err = json.NewEncoder(&buf).Encode(stores)
+		} else {
+			err = debugTemplate.Execute(&buf, stores)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		// Write errors mean the client went away: there is nobody to tell.
+		_, _ = buf.WriteTo(w)
+	})
+}
+
+// debugStores describes the requests with a store, oldest first.
+func debugStores() []DebugStore {
+	type item struct {
+		r       *http.Request
+		created int64
+	}
+	var items []item
+	currentBackend().Range(func(r *http.Request, s *Store) bool {
+		// created is only written when the store is created or recycled,
+		// before the backend hands it out.
+		items = append(items, item{r, s.created})
+		return true
+	})
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].created < items[j].created
+	})
+	current := now().UnixNano()
+	stores := make([]DebugStore, 0, len(items))
+	for _, it := range items {
+		d := DebugStore{
+			Method: it.r.Method,
+			Age:    time.Duration(current - it.created).Round(time.Millisecond).String(),
+			Values: debugValues(it.r),
+		}
+		d.Tenant = Tenant(it.r)
+		if it.r.URL != nil {
+			d.URL = it.r.URL.String()
+		}
+		sort.Slice(d.Values, func(i, j int) bool {
+			return d.Values[i].Key < d.Values[j].Key
+		})
+		stores = append(stores, d)
+	}
+	return stores
+}
+
+// debugValues describes the values of a request, with those inherited from
+// its parents. The reported type of values set with SetLazy and not computed
+// yet is "[lazy]": reports don't run factories.
+func debugValues(r *http.Request) []DebugEntry {
+	entries := []DebugEntry{}
+	seen := make(map[interface{}]bool)
+	for r != nil {
+		// Look the parent up first: the store of r is locked below.
+		p, hidden := inherited(r)
+		for k := range hidden {
+			seen[k] = true
+		}
+		if vs, s := view(r); vs != nil {
+			vs.eachRaw(func(k, v interface{}) bool {
+				if seen[k] {
+					return true
+				}
+				seen[k] = true
+				typ := "[lazy]"
+				if !pending(v) {
+					typ = fmt.Sprintf("%T", unbox(v))
+				}
+				entries = append(entries, DebugEntry{Key: keyName(k), Type: typ})
+				return true
+			})
+			if s != nil {
+				s.mu.RUnlock()
+			}
+		}
+		r = p
+	}
+	return entries
+}
+
+// keyName formats a key for reports. The type tells apart keys such as
+// constants of different packages with the same value.
+func keyName(k interface{}) string {
+	return fmt.Sprintf("%v (%T)", k, k)
+}
+
+var debugTemplate = template.Must(template.New("debug").Parse(`<!DOCTYPE html>
+<html>
+<head><title>gorilla/context</title></head>
+<body>
+<h1>{{len .}} active requests</h1>
+{{range .}}<h2>{{.Method}} {{.URL}} ({{.Age}}{{with .Tenant}}, tenant {{.}}{{end}})</h2>
+<table>
+<tr><th>Key</th><th>Type</th></tr>
+{{range .Values}}<tr><td>{{.Key}}</td><td>{{.Type}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
@@ -0,0 +1,42 @@
+package context
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDebugHandler(t *testing.T) {
+	r, _ := http.NewRequest("POST", "http://localhost:8080/leak", nil)
+	defer Clear(r)
+	Set(r, key1, "<secret>")
+
+	w := httptest.NewRecorder()
+	DebugHandler().ServeHTTP(w, httptest.NewRequest("GET", "/debug?format=json", nil))
+	var stores []DebugStore
+	if err := json.Unmarshal(w.Body.Bytes(), &stores); err != nil {
+		t.Fatal(err)
+	}
+	if len(stores) != 1 {
+		t.Fatalf("Expected 1 store, got %v", stores)
+	}
+	d := stores[0]
+	if d.Method != "POST" || d.URL != "http://localhost:8080/leak" || len(d.Values) != 1 {
+		t.Errorf("Unexpected report %+v", d)
+	}
+	if e := d.Values[0]; e.Key != "0 (context.keyType)" || e.Type != "string" {
+		t.Errorf("Unexpected entry %+v", e)
+	}
+
+	w = httptest.NewRecorder()
+	DebugHandler().ServeHTTP(w, httptest.NewRequest("GET", "/debug", nil))
+	body := w.Body.String()
+	if !strings.Contains(body, "POST http://localhost:8080/leak") {
+		t.Errorf("Expected the request in the HTML report, got %s", body)
+	}
+	if strings.Contains(body, "secret") {
+		t.Error("Expected values not to be shown")
+	}
+}
@@ -0,0 +1,29 @@
+package context
+
+import (
+	"net/http"
+)
+
+// Decorate registers a request, creating its empty store, so that
+// frameworks can do it at the top of their handler chain. Registering is
+// optional: Set registers requests as needed.
+//
+// Registered requests are reported by GetAllOk, and must be cleared like
+// any other.
+func Decorate(r *http.Request) {
+	attach(r).mu.Unlock()
+}
+
+// IsDecorated reports whether a request is registered, either by Decorate
+// or by storing a value, and not cleared since. It doesn't register the
+// request.
+func IsDecorated(r *http.Request) bool {
+	s := lookup(r)
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	ok := !s.cleared && s.ownedBy(r)
+	s.mu.RUnlock()
+	return ok
+}
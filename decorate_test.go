@@ -0,0 +1,28 @@
+package context
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestDecorate(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+
+	if IsDecorated(r) {
+		t.Error("Expected a new request not to be decorated")
+	}
+	if IsDecorated(r) || storeCount() != 0 {
+		t.Error("Expected IsDecorated not to register the request")
+	}
+	Decorate(r)
+	if !IsDecorated(r) {
+		t.Error("Expected the request to be decorated")
+	}
+	if values, ok := GetAllOk(r); !ok || len(values) != 0 {
+		t.Errorf("Expected an empty registered request, got %v %v", values, ok)
+	}
+	Clear(r)
+	if IsDecorated(r) {
+		t.Error("Expected Clear to undecorate the request")
+	}
+}
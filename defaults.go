@@ -0,0 +1,70 @@
+package context
+
+import (
+	"sync/atomic"
+)
+
+var (
+	// hasDefaults is set while global defaults exist: it is read by every
+	// Get that finds no value, so it is accessed atomically.
+	hasDefaults int64
+	// defaults holds the values set with SetGlobalDefault, as a
+	// map[interface{}]interface{} replaced on every change.
+	defaults atomic.Value
+)
+
+// SetGlobalDefault sets a value returned by Get, GetOk, GetS and Key.Get for
+// every request that has no value of its own for key, such as the
+// environment or the build version of the application. Values set for a
+// request shadow the default; deleting them makes it visible again. Defaults
+// are not returned by GetAll.
+func SetGlobalDefault(key, val interface{}) {
+	updateDefaults(func(m map[interface{}]interface{}) {
+		m[key] = val
+	})
+}
+
+// DeleteGlobalDefault removes a value set with SetGlobalDefault.
+func DeleteGlobalDefault(key interface{}) {
+	updateDefaults(func(m map[interface{}]interface{}) {
+		delete(m, key)
+	})
+}
+
+func updateDefaults(f func(m map[interface{}]interface{})) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	old, _ := defaults.Load().(map[interface{}]interface{})
+	m := make(map[interface{}]interface{}, len(old)+1)
+	for k, v := range old {
+		m[k] = v
+	}
+	f(m)
+	defaults.Store(m)
+	n := int64(0)
+	if len(m) > 0 {
+		n = 1
+	}
+	atomic.StoreInt64(&hasDefaults, n)
+}
+
+// getDefault returns the global default of key.
+func getDefault(key interface{}) (interface{}, bool) {
+	if loadInt(&hasDefaults) == 0 {
+		return nil, false
+	}
+	m, _ := defaults.Load().(map[interface{}]interface{})
+	v, ok := m[key]
+	return v, ok
+}
+
+// getDefaultS is getDefault for string keys, only converting the key to
+// interface{} if needed.
+func getDefaultS(key string) (interface{}, bool) {
+	if loadInt(&hasDefaults) == 0 {
+		return nil, false
+	}
+	m, _ := defaults.Load().(map[interface{}]interface{})
+	v, ok := m[key]
+	return v, ok
+}
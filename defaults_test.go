@@ -0,0 +1,37 @@
+package context
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestSetGlobalDefault(t *testing.T) {
+	k := NewKey[string]("locale")
+	SetGlobalDefault("env", "production")
+	SetGlobalDefault(k, "en")
+	defer DeleteGlobalDefault("env")
+	defer DeleteGlobalDefault(k)
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	if Get(r, "env") != "production" || GetS(r, "env") != "production" || k.Get(r) != "en" {
+		t.Error("Expected the defaults for a request without values")
+	}
+	if GetAll(r) != nil {
+		t.Error("Expected GetAll to leave defaults out")
+	}
+	Set(r, "env", "staging")
+	k.Set(r, "fr")
+	if Get(r, "env") != "staging" || k.Get(r) != "fr" {
+		t.Error("Expected request values to shadow the defaults")
+	}
+	Delete(r, "env")
+	if v, ok := GetOk(r, "env"); !ok || v != "production" {
+		t.Errorf("Expected the default once deleted, got %v", v)
+	}
+
+	DeleteGlobalDefault("env")
+	if _, ok := GetOk(r, "env"); ok {
+		t.Error("Expected the default to be removed")
+	}
+}
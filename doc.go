@@ -84,5 +84,11 @@ variables at the end of a request lifetime.
 
 The Routers from the packages gorilla/mux and gorilla/pat call Clear()
 so if you are using either of them you don't need to clear the context manually.
+
+Values are held by a registry inside the package, keyed by the request. If a
+program links two copies of the package, for example a vendored one and the
+module one, each copy has its own registry and doesn't see the values stored
+by the other. Make sure all code imports the same copy; with Go modules a
+build only ever contains one version of github.com/gorilla/context.
 */
 package context
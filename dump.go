@@ -0,0 +1,124 @@
+package context
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// maxDumpValue is the length values are truncated to by Dump and Diff.
+const maxDumpValue = 64
+
+// SortedKeys returns the keys of the values stored for a given request, like
+// Keys, in a stable order: by type name, then by value formatted with
+// fmt.Sprint. Dump and Diff use the same order.
+func SortedKeys(r *http.Request) []interface{} {
+	keys := Keys(r)
+	sortKeys(keys, nil)
+	return keys
+}
+
+// sortKeys sorts keys in the order of SortedKeys, moving the items of with,
+// if not nil, along with their key.
+func sortKeys(keys []interface{}, with []string) {
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = fmt.Sprintf("%T\x00%v", k, k)
+	}
+	sort.Sort(keyOrder{keys, names, with})
+}
+
+// keyOrder sorts keys by their names.
+type keyOrder struct {
+	keys  []interface{}
+	names []string
+	with  []string
+}
+
+func (o keyOrder) Len() int           { return len(o.keys) }
+func (o keyOrder) Less(i, j int) bool { return o.names[i] < o.names[j] }
+
+func (o keyOrder) Swap(i, j int) {
+	o.keys[i], o.keys[j] = o.keys[j], o.keys[i]
+	o.names[i], o.names[j] = o.names[j], o.names[i]
+	if o.with != nil {
+		o.with[i], o.with[j] = o.with[j], o.with[i]
+	}
+}
+
+// Dump returns a human-readable description of the values stored for a
+// request, one per line, in the order of SortedKeys:
+//
+//	key (type) = value (type)
+//
+// Values are formatted with fmt.Sprint and truncated, so Dump is meant for
+// logs and debugging rather than for recovering values. Values set with
+// SetOnce and not popped yet are marked with "[once]", and values set with
+// SetLazy and not computed yet are shown as "[lazy]".
+func Dump(r *http.Request) string {
+	vs, s := view(r)
+	if vs == nil {
+		return ""
+	}
+	keys := make([]interface{}, 0, vs.len())
+	lines := make([]string, 0, vs.len())
+	vs.eachRaw(func(k, v interface{}) bool {
+		var line string
+		if l, ok := v.(*lazyValue); ok && !l.done.Load() {
+			line = keyName(k) + " = [lazy]"
+		} else {
+			line = keyName(k) + " = " + dumpValue(Redact(k, unbox(v)))
+		}
+		if _, ok := v.(onceValue); ok {
+			line += " [once]"
+		}
+		keys = append(keys, k)
+		lines = append(lines, line)
+		return true
+	})
+	if s != nil {
+		s.mu.RUnlock()
+	}
+	sortKeys(keys, lines)
+	return strings.Join(lines, "\n")
+}
+
+// Diff describes the differences between two sets of values, as returned by
+// GetAll, one per line, in the order of SortedKeys. Added values start with "+", removed
+// ones with "-", and changed ones with "~". It returns "" if the values are
+// deeply equal.
+func Diff(before, after map[interface{}]interface{}) string {
+	var (
+		keys  []interface{}
+		lines []string
+	)
+	for k, b := range before {
+		a, ok := after[k]
+		switch {
+		case !ok:
+			keys = append(keys, k)
+			lines = append(lines, "- "+keyName(k)+" = "+dumpValue(Redact(k, b)))
+		case !reflect.DeepEqual(a, b):
+			keys = append(keys, k)
+			lines = append(lines, "~ "+keyName(k)+" = "+dumpValue(Redact(k, b))+" -> "+dumpValue(Redact(k, a)))
+		}
+	}
+	for k, a := range after {
+		if _, ok := before[k]; !ok {
+			keys = append(keys, k)
+			lines = append(lines, "+ "+keyName(k)+" = "+dumpValue(Redact(k, a)))
+		}
+	}
+	sortKeys(keys, lines)
+	return strings.Join(lines, "\n")
+}
+
+func dumpValue(v interface{}) string {
+	s := fmt.Sprint(v)
+	if len(s) > maxDumpValue {
+		s = s[:maxDumpValue] + "..."
+	}
+	return fmt.Sprintf("%s (%T)", s, v)
+}
@@ -0,0 +1,60 @@
+package context
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	Set(r, "b", strings.Repeat("x", 100))
+	Set(r, "a", 1)
+
+	want := "a (string) = 1 (int)\nb (string) = " + strings.Repeat("x", 64) + "... (string)"
+	if got := Dump(r); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	before := map[interface{}]interface{}{"a": 1, "b": []int{1}, "c": "c"}
+	after := map[interface{}]interface{}{"a": 2, "b": []int{1}, "d": "d"}
+
+	want := "~ a (string) = 1 (int) -> 2 (int)\n" +
+		"- c (string) = c (string)\n" +
+		"+ d (string) = d (string)"
+	if got := Diff(before, after); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if got := Diff(before, before); got != "" {
+		t.Errorf("Expected no difference, got %q", got)
+	}
+}
+
+func TestSortedKeys(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	Set(r, "b", 1)
+	Set(r, key2, 2)
+	Set(r, 3, 3)
+	Set(r, "a", 4)
+	Set(r, key1, 5)
+
+	want := []interface{}{key1, key2, 3, "a", "b"}
+	for i := 0; i < 10; i++ {
+		if got := SortedKeys(r); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Expected %v, got %v", want, got)
+		}
+	}
+	wantDump := "0 (context.keyType) = 5 (int)\n" +
+		"1 (context.keyType) = 2 (int)\n" +
+		"3 (int) = 3 (int)\n" +
+		"a (string) = 4 (int)\n" +
+		"b (string) = 1 (int)"
+	if got := Dump(r); got != wantDump {
+		t.Errorf("Expected %q, got %q", wantDump, got)
+	}
+}
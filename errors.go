@@ -0,0 +1,83 @@
+package context
+
+import (
+	"net/http"
+	"strings"
+)
+
+// AddError records an error for a request, so that layers such as
+// validation can report several problems without returning them through
+// every caller. Nil errors are ignored.
+func AddError(r *http.Request, err error) {
+	if err == nil {
+		return
+	}
+	WithStore(r, func(s MutableStore) {
+		errs, _ := s.Get(errorsKey).([]error)
+		s.Set(errorsKey, append(errs[:len(errs):len(errs)], err))
+	})
+}
+
+// Errors returns the errors recorded for a request with AddError, in order.
+// The returned slice must not be modified.
+func Errors(r *http.Request) []error {
+	errs, _ := Get(r, errorsKey).([]error)
+	return errs
+}
+
+// FirstError returns the first error recorded for a request, or nil.
+func FirstError(r *http.Request) error {
+	if errs := Errors(r); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// ErrorRenderer writes the response of a request with errors.
+type ErrorRenderer func(w http.ResponseWriter, r *http.Request, errs []error)
+
+// ErrorHandler wraps h to render the errors recorded with AddError once h
+// returns. render is only called if h wrote no response; if nil, the errors
+// are written one per line with a 400 Bad Request status.
+func ErrorHandler(h http.Handler, render ErrorRenderer) http.Handler {
+	if render == nil {
+		render = renderErrors
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := &writtenWriter{ResponseWriter: w}
+		h.ServeHTTP(ww, r)
+		if errs := Errors(r); len(errs) > 0 && !ww.written {
+			render(w, r, errs)
+		}
+	})
+}
+
+// renderErrors is the default ErrorRenderer.
+func renderErrors(w http.ResponseWriter, r *http.Request, errs []error) {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	http.Error(w, strings.Join(msgs, "\n"), http.StatusBadRequest)
+}
+
+// writtenWriter records whether a response was written.
+type writtenWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *writtenWriter) WriteHeader(code int) {
+	w.written = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *writtenWriter) Write(b []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController and SetW.
+func (w *writtenWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,51 @@
+package context
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	if FirstError(r) != nil || Errors(r) != nil {
+		t.Error("Expected no errors")
+	}
+	errName, errAge := errors.New("name is required"), errors.New("age is invalid")
+	AddError(r, errName)
+	AddError(r, nil)
+	AddError(r, errAge)
+	if errs := Errors(r); len(errs) != 2 || errs[1] != errAge {
+		t.Errorf("Unexpected errors %v", errs)
+	}
+	if FirstError(r) != errName {
+		t.Errorf("Expected the first error, got %v", FirstError(r))
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	h := ErrorHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		AddError(r, errors.New("name is required"))
+		AddError(r, errors.New("age is invalid"))
+	}), nil)
+	rec := httptest.NewRecorder()
+	ClearHandler(h).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusBadRequest || rec.Body.String() != "name is required\nage is invalid\n" {
+		t.Errorf("Unexpected response %d %q", rec.Code, rec.Body.String())
+	}
+
+	// Responses written by the handler are left alone.
+	h = ErrorHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		AddError(r, errors.New("ignored"))
+		w.WriteHeader(http.StatusAccepted)
+	}), func(w http.ResponseWriter, r *http.Request, errs []error) {
+		t.Error("Unexpected call to the renderer")
+	})
+	rec = httptest.NewRecorder()
+	ClearHandler(h).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("Expected status %d, got %d", http.StatusAccepted, rec.Code)
+	}
+}
@@ -0,0 +1,145 @@
+package context
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ExtractHeaders returns middleware storing the request headers named by
+// the keys of mapping under the matching values, so that handlers read them
+// like any other request value:
+//
+//	userAgent := context.NewKey[string]("user agent")
+//	h = context.ExtractHeaders(map[string]interface{}{
+//		"Accept-Language": "lang",
+//		"User-Agent":      userAgent,
+//		"Max-Forwards":    context.Param{Key: "hops", Type: context.IntParam},
+//	})(h)
+//
+// The first value of every header is stored as a string, and can be read
+// with a Key[string], unless the mapped value is a Param: the header is then
+// converted and defaulted as by ExtractQuery, and the Name of the Param is
+// ignored. Headers that are not present are not stored.
+func ExtractHeaders(mapping map[string]interface{}) func(http.Handler) http.Handler {
+	// Build the params from a copy: callers may change theirs afterwards.
+	params := make([]Param, 0, len(mapping))
+	for name, key := range mapping {
+		p, ok := key.(Param)
+		if !ok {
+			p = Param{Key: key}
+		}
+		p.Name = http.CanonicalHeaderKey(name)
+		params = append(params, p)
+	}
+	return extractParams(params, func(r *http.Request, name string) (string, bool) {
+		if v := r.Header[name]; len(v) > 0 {
+			return v[0], true
+		}
+		return "", false
+	})
+}
+
+// ParamType is the type a parameter is converted to by ExtractCookies,
+// ExtractQuery and ExtractHeaders.
+type ParamType int
+
+const (
+	// StringParam values are stored as is. This is the default.
+	StringParam ParamType = iota
+	// IntParam values are parsed with strconv.Atoi and stored as ints.
+	IntParam
+	// BoolParam values are parsed with strconv.ParseBool.
+	BoolParam
+	// TimeParam values are parsed as RFC 3339 times and stored as
+	// time.Time.
+	TimeParam
+)
+
+// Param describes a request parameter stored by ExtractCookies,
+// ExtractQuery and ExtractHeaders.
+type Param struct {
+	// Name is the name of the cookie or query parameter. ExtractHeaders
+	// uses the header name instead.
+	Name string
+	// Key is the key the value is stored under.
+	Key interface{}
+	// Type is the type the value is converted to.
+	Type ParamType
+	// Default, if not nil, is stored when the parameter is missing or can't
+	// be converted. Otherwise nothing is stored.
+	Default interface{}
+}
+
+// ExtractCookies returns middleware storing the cookies described by params,
+// converted to their type:
+//
+//	h = context.ExtractCookies(
+//		context.Param{Name: "theme", Key: "theme", Default: "light"},
+//		context.Param{Name: "visits", Key: "visits", Type: context.IntParam},
+//	)(h)
+func ExtractCookies(params ...Param) func(http.Handler) http.Handler {
+	return extractParams(params, func(r *http.Request, name string) (string, bool) {
+		c, err := r.Cookie(name)
+		if err != nil {
+			return "", false
+		}
+		return c.Value, true
+	})
+}
+
+// ExtractQuery returns middleware storing the query parameters described by
+// params, converted to their type. The first value of every parameter is
+// used.
+func ExtractQuery(params ...Param) func(http.Handler) http.Handler {
+	return extractParams(params, func(r *http.Request, name string) (string, bool) {
+		v, ok := r.URL.Query()[name]
+		if !ok || len(v) == 0 {
+			return "", false
+		}
+		return v[0], true
+	})
+}
+
+func extractParams(params []Param, get func(r *http.Request, name string) (string, bool)) func(http.Handler) http.Handler {
+	params = append([]Param(nil), params...)
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, p := range params {
+				var v interface{}
+				if s, ok := get(r, p.Name); ok {
+					v = p.Type.convert(s)
+				}
+				if v == nil {
+					v = p.Default
+				}
+				if v != nil {
+					Set(r, p.Key, v)
+				}
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// convert returns s converted to t, or nil if it can't be.
+func (t ParamType) convert(s string) interface{} {
+	var (
+		v   interface{}
+		err error
+	)
+	switch t {
+	case IntParam:
+		v, err = strconv.Atoi(s)
+	case BoolParam:
+		v, err = strconv.ParseBool(s)
+	case TimeParam:
+		v, err = time.Parse(time.RFC3339, s)
+	default:
+		v = s
+	}
+	if err != nil {
+		return nil
+	}
+	return v
+}
@@ -0,0 +1,76 @@
+package context
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestExtractHeaders(t *testing.T) {
+	userAgent := NewKey[string]("user agent")
+	mw := ExtractHeaders(map[string]interface{}{
+		"accept-language": "lang",
+		"User-Agent":      userAgent,
+		"X-Missing":       key1,
+		"Max-Forwards":    Param{Key: "hops", Type: IntParam},
+		"X-Debug":         Param{Key: "debug", Type: BoolParam, Default: false},
+	})
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	r.Header.Set("Accept-Language", "fr")
+	r.Header.Set("User-Agent", "test")
+	r.Header.Set("Max-Forwards", "3")
+	r.Header.Set("X-Debug", "maybe")
+	ClearHandler(mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v := Get(r, "lang"); v != "fr" {
+			t.Errorf("Expected fr, got %v", v)
+		}
+		if v := userAgent.Get(r); v != "test" {
+			t.Errorf("Expected test, got %q", v)
+		}
+		if _, ok := GetOk(r, key1); ok {
+			t.Error("Expected missing headers not to be stored")
+		}
+		if v := Get(r, "hops"); v != 3 {
+			t.Errorf("Expected 3 hops, got %#v", v)
+		}
+		if v := Get(r, "debug"); v != false {
+			t.Errorf("Expected the default for an invalid header, got %#v", v)
+		}
+	}))).ServeHTTP(httptest.NewRecorder(), r)
+}
+
+func TestExtractQuery(t *testing.T) {
+	mw := ExtractQuery(
+		Param{Name: "q", Key: "q"},
+		Param{Name: "page", Key: "page", Type: IntParam, Default: 1},
+		Param{Name: "debug", Key: "debug", Type: BoolParam},
+		Param{Name: "since", Key: "since", Type: TimeParam},
+		Param{Name: "missing", Key: "missing"},
+	)
+	r, _ := http.NewRequest("GET", "http://localhost:8080/?q=go&page=x&debug=true&since=2012-10-01T00:00:00Z", nil)
+	ClearHandler(mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		since := time.Date(2012, 10, 1, 0, 0, 0, 0, time.UTC)
+		if Get(r, "q") != "go" || Get(r, "page") != 1 || Get(r, "debug") != true || !Get(r, "since").(time.Time).Equal(since) {
+			t.Errorf("Unexpected values %v", GetAll(r))
+		}
+		if _, ok := GetOk(r, "missing"); ok {
+			t.Error("Expected missing parameters without default not to be stored")
+		}
+	}))).ServeHTTP(httptest.NewRecorder(), r)
+}
+
+func TestExtractCookies(t *testing.T) {
+	mw := ExtractCookies(
+		Param{Name: "visits", Key: "visits", Type: IntParam},
+		Param{Name: "theme", Key: "theme", Default: "light"},
+	)
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	r.AddCookie(&http.Cookie{Name: "visits", Value: "3"})
+	ClearHandler(mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if Get(r, "visits") != 3 || Get(r, "theme") != "light" {
+			t.Errorf("Unexpected values %v", GetAll(r))
+		}
+	}))).ServeHTTP(httptest.NewRecorder(), r)
+}
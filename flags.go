@@ -0,0 +1,82 @@
+package context
+
+import (
+	"net/http"
+)
+
+// FlagEvaluator decides whether feature flags are enabled for a request.
+type FlagEvaluator interface {
+	Evaluate(r *http.Request, flag string) bool
+}
+
+// FlagEvaluatorFunc adapts a function to FlagEvaluator.
+type FlagEvaluatorFunc func(r *http.Request, flag string) bool
+
+// Evaluate calls f.
+func (f FlagEvaluatorFunc) Evaluate(r *http.Request, flag string) bool {
+	return f(r, flag)
+}
+
+// flagEvaluator is the evaluator set with SetFlagEvaluator.
+var flagEvaluator FlagEvaluator
+
+// SetFlagEvaluator sets the evaluator used by Flags. It is meant to be
+// called during program initialization. Without an evaluator, all flags
+// are disabled.
+func SetFlagEvaluator(e FlagEvaluator) {
+	mutex.Lock()
+	flagEvaluator = e
+	mutex.Unlock()
+}
+
+// FlagSet gives access to the feature flags of a request.
+type FlagSet struct {
+	r *http.Request
+}
+
+// Flags returns the feature flags of a request. Every flag is evaluated at
+// most once per request, by the evaluator set with SetFlagEvaluator, so that
+// checking flags deep in the call tree is cheap, and the answer doesn't
+// change during the request.
+func Flags(r *http.Request) FlagSet {
+	return FlagSet{r}
+}
+
+// Enabled reports whether a flag is enabled for the request.
+func (f FlagSet) Enabled(flag string) bool {
+	var (
+		on     bool
+		cached bool
+	)
+	WithStore(f.r, func(s MutableStore) {
+		m, _ := s.Get(flagsKey).(map[string]bool)
+		on, cached = m[flag]
+	})
+	if cached {
+		return on
+	}
+	mutex.RLock()
+	e := flagEvaluator
+	mutex.RUnlock()
+	if e != nil {
+		// Evaluate without the store lock: evaluators may use the package.
+		on = e.Evaluate(f.r, flag)
+	}
+	WithStore(f.r, func(s MutableStore) {
+		m, _ := s.Get(flagsKey).(map[string]bool)
+		if prev, ok := m[flag]; ok {
+			// Evaluated concurrently: keep the first answer.
+			on = prev
+			return
+		}
+		// The map may have been handed out by snapshots of the store, such
+		// as those of GetAll: copy.
+		flags := make(map[string]bool, len(m)+1)
+		for k, v := range m {
+			flags[k] = v
+		}
+		flags[flag] = on
+		s.Set(flagsKey, flags)
+	})
+	return on
+}
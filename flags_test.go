@@ -0,0 +1,69 @@
+package context
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestFlags(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	if Flags(r).Enabled("new-ui") {
+		t.Error("Expected flags to be disabled without an evaluator")
+	}
+	Clear(r)
+
+	evaluations := 0
+	SetFlagEvaluator(FlagEvaluatorFunc(func(r *http.Request, flag string) bool {
+		evaluations++
+		return flag == "new-ui"
+	}))
+	defer SetFlagEvaluator(nil)
+
+	for i := 0; i < 3; i++ {
+		if !Flags(r).Enabled("new-ui") || Flags(r).Enabled("beta") {
+			t.Error("Unexpected flag values")
+		}
+	}
+	if evaluations != 2 {
+		t.Errorf("Expected 2 evaluations, got %d", evaluations)
+	}
+
+	r2, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r2)
+	Flags(r2).Enabled("new-ui")
+	if evaluations != 3 {
+		t.Errorf("Expected flags to be evaluated again for another request, got %d", evaluations)
+	}
+}
+
+func TestFlagsSnapshot(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	SetFlagEvaluator(FlagEvaluatorFunc(func(r *http.Request, flag string) bool {
+		return true
+	}))
+	defer SetFlagEvaluator(nil)
+	Flags(r).Enabled("first")
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			Flags(r).Enabled(strconv.Itoa(i))
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		// Run with -race: the maps read here must not be changed by Enabled.
+		m, _ := Internal(r)["flags"].(map[string]bool)
+		for range m {
+		}
+	}
+	wg.Wait()
+	if m, _ := Internal(r)["flags"].(map[string]bool); len(m) != 101 {
+		t.Errorf("Expected 101 flags, got %d", len(m))
+	}
+}
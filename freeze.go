@@ -0,0 +1,78 @@
+package context
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+)
+
+// ErrFrozen is returned by SetE and DeleteE for requests frozen with Freeze.
+var ErrFrozen = errors.New("context: request values are frozen")
+
+// freezeStrict is set by SetFreezeStrict. It is read whenever a change is
+// refused, so it is accessed atomically.
+var freezeStrict int64
+
+// Freeze prevents the values of a request from changing until it is
+// cleared: Set and Delete leave them alone, and SetE and DeleteE return
+// ErrFrozen. Clear still removes them.
+//
+// A middleware storing the authenticated principal calls Freeze before the
+// rest of the chain, so that nothing downstream can overwrite it. With
+// SetFreezeStrict, changes to frozen requests panic instead.
+//
+// The user, tenant, baggage, logger and body of the request are frozen too.
+// AddError, Cache, Flags and TraceClient keep recording what happens while
+// the request is served.
+func Freeze(r *http.Request) {
+	s := attach(r)
+	s.frozen = true
+	s.mu.Unlock()
+}
+
+// SetFreezeStrict makes Set, SetE, SetS, Delete, DeleteE and Key.Set panic
+// with ErrFrozen for requests frozen with Freeze, so that tests point at the
+// code trying to change them. It is off by default.
+func SetFreezeStrict(on bool) {
+	if on {
+		atomic.StoreInt64(&freezeStrict, 1)
+	} else {
+		atomic.StoreInt64(&freezeStrict, 0)
+	}
+}
+
+// IsFrozen reports whether a request was frozen with Freeze.
+func IsFrozen(r *http.Request) bool {
+	s := lookup(r)
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ownedBy(r) && s.frozen
+}
+
+// writable returns ErrFrozen if the store is frozen and key is not one of
+// the internal keys updated while serving the request. It must be called
+// with the store locked.
+func (s *Store) writable(key interface{}) error {
+	if s.frozen {
+		switch key {
+		case errorsKey, cacheKey, flagsKey, clientTraceKey:
+			// Bookkeeping rather than values Freeze protects: AddError,
+			// Cache, Flags and the client traces keep working.
+		default:
+			return ErrFrozen
+		}
+	}
+	return nil
+}
+
+// checkFrozen panics with err if it is ErrFrozen and SetFreezeStrict is on,
+// and returns it otherwise. It must be called without the store locked.
+func checkFrozen(err error) error {
+	if err == ErrFrozen && loadInt(&freezeStrict) != 0 {
+		panic(err)
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+package context
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestFreeze(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	k := NewKey[string]("user")
+	Set(r, key1, "1")
+	SetOnce(r, "once", "1")
+	k.Set(r, "gopher")
+	Freeze(r)
+	if !IsFrozen(r) {
+		t.Fatal("Expected the request to be frozen")
+	}
+
+	if err := SetE(r, key1, "2"); err != ErrFrozen {
+		t.Errorf("Expected ErrFrozen, got %v", err)
+	}
+	if err := DeleteE(r, key1); err != ErrFrozen {
+		t.Errorf("Expected ErrFrozen, got %v", err)
+	}
+	SetS(r, "key", "1")
+	k.Set(r, "alice")
+	if _, ok := PopOnce(r, "once"); ok {
+		t.Error("Expected frozen values not to be consumed")
+	}
+	if Get(r, key1) != "1" || GetS(r, "key") != nil || k.Get(r) != "gopher" || Get(r, "once") != "1" {
+		t.Errorf("Expected the values to be unchanged, got %v", GetAll(r))
+	}
+
+	Clear(r)
+	if IsFrozen(r) || SetE(r, key1, "2") != nil {
+		t.Error("Expected Clear to unfreeze the request")
+	}
+}
+
+func TestFreezeStrict(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	Freeze(r)
+
+	// The leak detection of SetStrictMode doesn't make changes panic.
+	SetStrictMode(&StrictMode{})
+	Set(r, key1, "1")
+	SetStrictMode(nil)
+
+	SetFreezeStrict(true)
+	defer SetFreezeStrict(false)
+	defer func() {
+		if recover() != ErrFrozen {
+			t.Error("Expected a panic with ErrFrozen")
+		}
+	}()
+	Set(r, key1, "1")
+}
+
+func TestFreezeBookkeeping(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	SetUser(r, "gopher")
+	Freeze(r)
+
+	errBoom := errors.New("boom")
+	AddError(r, errBoom)
+	if errs := Errors(r); len(errs) != 1 || errs[0] != errBoom {
+		t.Errorf("Expected AddError to work on frozen requests, got %v", errs)
+	}
+	if Cache(r) != Cache(r) {
+		t.Error("Expected Cache to be stored once for frozen requests")
+	}
+	evaluations := 0
+	SetFlagEvaluator(FlagEvaluatorFunc(func(r *http.Request, flag string) bool {
+		evaluations++
+		return true
+	}))
+	defer SetFlagEvaluator(nil)
+	Flags(r).Enabled("new-ui")
+	Flags(r).Enabled("new-ui")
+	if evaluations != 1 {
+		t.Errorf("Expected flags to be cached for frozen requests, got %d evaluations", evaluations)
+	}
+	SetUser(r, "admin")
+	if User(r) != "gopher" {
+		t.Errorf("Expected the user to stay frozen, got %v", User(r))
+	}
+}
@@ -0,0 +1,35 @@
+package context
+
+import (
+	"html/template"
+	"net/http"
+)
+
+// FuncMap returns template functions reading the values stored for a
+// request, for html/template and text/template:
+//
+//	ctx KEY      the value stored for the string key KEY, or nil
+//	ctxOk KEY    whether a value is stored for KEY
+//
+// Templates can then use request values without handlers copying them into
+// the template data:
+//
+//	t, err := template.New("page").Funcs(context.FuncMap(r)).Parse(`Hello, {{ctx "user"}}`)
+//
+// The map is of the html/template type; convert it for text/template:
+//
+//	texttemplate.FuncMap(context.FuncMap(r))
+//
+// Templates parsed once and shared by requests must be cloned with Clone
+// before replacing their functions for every request.
+func FuncMap(r *http.Request) template.FuncMap {
+	return template.FuncMap{
+		"ctx": func(key string) interface{} {
+			return Get(r, key)
+		},
+		"ctxOk": func(key string) bool {
+			_, ok := GetOk(r, key)
+			return ok
+		},
+	}
+}
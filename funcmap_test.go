@@ -0,0 +1,34 @@
+package context
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"testing"
+	texttemplate "text/template"
+)
+
+func TestFuncMap(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	Set(r, "user", "<gopher>")
+
+	const text = `{{ctx "user"}} {{ctxOk "user"}} {{ctxOk "missing"}}`
+	var buf bytes.Buffer
+	tmpl := template.Must(template.New("html").Funcs(FuncMap(r)).Parse(text))
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "&lt;gopher&gt; true false" {
+		t.Errorf("Unexpected output %q", got)
+	}
+
+	buf.Reset()
+	ttmpl := texttemplate.Must(texttemplate.New("text").Funcs(texttemplate.FuncMap(FuncMap(r))).Parse(text))
+	if err := ttmpl.Execute(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "<gopher> true false" {
+		t.Errorf("Unexpected output %q", got)
+	}
+}
@@ -0,0 +1,108 @@
+package context
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"net/http"
+)
+
+// snapshotKeys maps the names registered with RegisterSnapshotKey to their
+// keys, and snapshotNames the keys to their names.
+var (
+	snapshotKeys  = map[string]interface{}{}
+	snapshotNames = map[interface{}]string{}
+)
+
+// RegisterSnapshotKey registers a key whose values are included by
+// MarshalBinary, under a name identifying it across processes. The types of
+// the values must be registered with gob.Register.
+//
+// It panics if the name or the key is already registered. It is meant to be
+// called during program initialization.
+func RegisterSnapshotKey(name string, key interface{}) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if _, ok := snapshotKeys[name]; ok {
+		panic("context: snapshot key name registered twice: " + name)
+	}
+	if _, ok := snapshotNames[key]; ok {
+		panic("context: snapshot key registered twice: " + name)
+	}
+	snapshotKeys[name] = key
+	snapshotNames[key] = name
+}
+
+// gobValue carries a value of any registered type.
+type gobValue struct {
+	V interface{}
+}
+
+// MarshalBinary encodes the values stored for a request under keys
+// registered with RegisterSnapshotKey with encoding/gob, for Restore to
+// store them for a request in another process. Servers handing their
+// connections over to a new process on graceful restart can hand over their
+// values too.
+//
+// Values that gob can't encode are left out.
+func MarshalBinary(r *http.Request) ([]byte, error) {
+	values := GetAll(r)
+	entries := make(map[string][]byte)
+	mutex.RLock()
+	for k, v := range values {
+		name, ok := snapshotNames[k]
+		if !ok {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(&gobValue{v}); err != nil {
+			continue
+		}
+		entries[name] = buf.Bytes()
+	}
+	mutex.RUnlock()
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(entries); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ErrUnknownSnapshotKey is returned by Restore for values whose key name is
+// not registered.
+var ErrUnknownSnapshotKey = errors.New("context: unknown snapshot key")
+
+// Restore stores the values encoded by MarshalBinary for a request. Values
+// with unknown key names are skipped, and reported by ErrUnknownSnapshotKey
+// after storing the others. Nothing is stored if data can't be decoded.
+func Restore(r *http.Request, data []byte) error {
+	var entries map[string][]byte
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entries); err != nil {
+		return err
+	}
+	values := make(map[interface{}]interface{}, len(entries))
+	var first error
+	mutex.RLock()
+	for name, b := range entries {
+		key, ok := snapshotKeys[name]
+		if !ok {
+			first = ErrUnknownSnapshotKey
+			continue
+		}
+		var v gobValue
+		if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&v); err != nil {
+			mutex.RUnlock()
+			return err
+		}
+		values[key] = v.V
+	}
+	mutex.RUnlock()
+	WithStore(r, func(s MutableStore) {
+		for k, v := range values {
+			if err := s.SetE(k, v); err != nil && first == nil {
+				first = err
+			}
+		}
+	})
+	return first
+}
@@ -0,0 +1,52 @@
+package context
+
+import (
+	"bytes"
+	"encoding/gob"
+	"net/http"
+	"testing"
+)
+
+type gobUser struct {
+	Name  string
+	Admin bool
+}
+
+func init() {
+	gob.Register(gobUser{})
+	RegisterSnapshotKey("test.user", key1)
+	RegisterSnapshotKey("test.count", "count")
+	RegisterSnapshotKey("test.func", "func")
+}
+
+func TestMarshalBinary(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	Set(r, key1, gobUser{"gopher", true})
+	Set(r, "count", 3)
+	Set(r, "func", func() {})
+	Set(r, "unregistered", "x")
+
+	data, err := MarshalBinary(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(restored)
+	if err := Restore(restored, data); err != nil {
+		t.Fatal(err)
+	}
+	values := GetAll(restored)
+	if len(values) != 2 || values[key1] != (gobUser{"gopher", true}) || values["count"] != 3 {
+		t.Errorf("Unexpected values %v", values)
+	}
+
+	var buf bytes.Buffer
+	gob.NewEncoder(&buf).Encode(map[string][]byte{"unknown": nil})
+	if err := Restore(restored, buf.Bytes()); err != ErrUnknownSnapshotKey {
+		t.Errorf("Expected ErrUnknownSnapshotKey, got %v", err)
+	}
+	if err := Restore(restored, []byte("garbage")); err == nil {
+		t.Error("Expected an error for invalid data")
+	}
+}
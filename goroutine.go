@@ -0,0 +1,117 @@
+package context
+
+import (
+	"net/http"
+	"sync"
+)
+
+// Accessor gives a goroutine access to the values of a request, as returned
+// by ForGoroutine. Reads see the values as they were when the accessor was
+// created, along with its own writes, and writes stay in the accessor until
+// Join. It may be used by several goroutines.
+type Accessor struct {
+	r       *http.Request
+	mu      sync.Mutex
+	values  map[interface{}]interface{}
+	changes map[interface{}]change
+}
+
+// change is a write made through an Accessor.
+type change struct {
+	val     interface{}
+	deleted bool
+}
+
+// ForGoroutine returns an accessor for the values of r, for handlers that
+// fan work out to goroutines:
+//
+//	a := context.ForGoroutine(r)
+//	for _, part := range parts {
+//		wg.Add(1)
+//		go func(part string) {
+//			defer wg.Done()
+//			a.Set(part, fetch(part, a.Get(userKey)))
+//		}(part)
+//	}
+//	wg.Wait()
+//	a.Join()
+//
+// Accessors never see each other's writes, nor those the handler made after
+// ForGoroutine returned.
+func ForGoroutine(r *http.Request) *Accessor {
+	values, _ := collectValues(r, true, true)
+	return &Accessor{r: r, values: values}
+}
+
+// Get returns the value stored for key.
+func (a *Accessor) Get(key interface{}) interface{} {
+	v, _ := a.GetOk(key)
+	return v
+}
+
+// GetOk returns the value stored for key and whether it was present.
+func (a *Accessor) GetOk(key interface{}) (interface{}, bool) {
+	a.mu.Lock()
+	if c, ok := a.changes[key]; ok {
+		a.mu.Unlock()
+		return c.val, !c.deleted
+	}
+	v, ok := a.values[key]
+	a.mu.Unlock()
+	// Values set with SetLazy are computed on first read, outside the lock.
+	return load(v), ok
+}
+
+// Set stores a value for key in the accessor.
+func (a *Accessor) Set(key, val interface{}) {
+	a.record(key, change{val: val})
+}
+
+// Delete removes the value stored for key in the accessor.
+func (a *Accessor) Delete(key interface{}) {
+	a.record(key, change{deleted: true})
+}
+
+func (a *Accessor) record(key interface{}, c change) {
+	a.mu.Lock()
+	if a.changes == nil {
+		a.changes = make(map[interface{}]change)
+	}
+	a.changes[key] = c
+	a.mu.Unlock()
+}
+
+// Join applies the writes made through the accessor to the request, in no
+// particular order, overwriting the values set by the handler or other
+// accessors in the meantime. It returns the first error reported by SetE or
+// DeleteE, after applying the others. The accessor may be used again
+// afterwards: the next Join only applies the new writes.
+func (a *Accessor) Join() error {
+	a.mu.Lock()
+	changes := a.changes
+	a.changes = nil
+	for k, c := range changes {
+		if c.deleted {
+			delete(a.values, k)
+		} else {
+			if a.values == nil {
+				a.values = make(map[interface{}]interface{})
+			}
+			a.values[k] = c.val
+		}
+	}
+	a.mu.Unlock()
+	var first error
+	for k, c := range changes {
+		var err error
+		if c.deleted {
+			err = DeleteE(a.r, k)
+		} else {
+			err = SetE(a.r, k, c.val)
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,67 @@
+package context
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestForGoroutine(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	Set(r, key1, "1")
+	Set(r, key2, "2")
+
+	var wg sync.WaitGroup
+	accessors := make([]*Accessor, 4)
+	for i := range accessors {
+		a := ForGoroutine(r)
+		accessors[i] = a
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if a.Get(key1) != "1" {
+				t.Errorf("Expected the request values, got %v", a.Get(key1))
+			}
+			a.Set(i, i)
+			if i == 0 {
+				a.Delete(key2)
+			}
+		}(i)
+	}
+	Set(r, key1, "changed")
+	wg.Wait()
+
+	if _, ok := GetOk(r, 0); ok {
+		t.Error("Expected writes to stay in the accessor until Join")
+	}
+	if _, ok := accessors[0].GetOk(key2); ok {
+		t.Error("Expected the accessor to see its own deletion")
+	}
+	if _, ok := accessors[1].GetOk(0); ok {
+		t.Error("Expected accessors not to see each other's writes")
+	}
+	for _, a := range accessors {
+		if err := a.Join(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := range accessors {
+		if v := Get(r, i); v != i {
+			t.Errorf("Expected %d after Join, got %v", i, v)
+		}
+	}
+	if _, ok := GetOk(r, key2); ok {
+		t.Error("Expected the deletion to be applied")
+	}
+	if Get(r, key1) != "changed" {
+		t.Error("Expected Join to keep values the accessors didn't write")
+	}
+
+	Freeze(r)
+	a := ForGoroutine(r)
+	a.Set(key1, "late")
+	if err := a.Join(); err != ErrFrozen {
+		t.Errorf("Expected ErrFrozen, got %v", err)
+	}
+}
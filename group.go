@@ -0,0 +1,72 @@
+package context
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// KeyGroup tags keys that belong together, such as all the caching hints or
+// all the auth data of a request, so that they can be read or removed as a
+// unit. Groups with the same name share their keys.
+type KeyGroup struct {
+	name string
+}
+
+// Group returns the group of keys named name:
+//
+//	var (
+//		auth      = context.Group("auth")
+//		userKey   = auth.Key("user")
+//		scopesKey = auth.Key("scopes")
+//	)
+//
+//	context.Set(r, userKey, user)
+//	// ...
+//	context.ClearGroup(r, auth)
+func Group(name string) *KeyGroup {
+	return &KeyGroup{name: name}
+}
+
+// Name returns the name of the group.
+func (g *KeyGroup) Name() string {
+	return g.name
+}
+
+// Key returns key tagged with the group, to be used with the package
+// functions. It is distinct from key itself and from the keys of other
+// groups.
+func (g *KeyGroup) Key(key interface{}) interface{} {
+	return groupKey{g.name, key}
+}
+
+// groupKey is the key of the values of a KeyGroup.
+type groupKey struct {
+	group string
+	key   interface{}
+}
+
+func (k groupKey) String() string {
+	return k.group + ":" + fmt.Sprint(k.key)
+}
+
+// ClearGroup removes all values stored for a given request under the keys of
+// group g.
+func ClearGroup(r *http.Request, g *KeyGroup) {
+	deleteMatching(r, func(k interface{}) bool {
+		gk, ok := k.(groupKey)
+		return ok && gk.group == g.name
+	})
+}
+
+// GetGroup returns the values stored for a given request under the keys of
+// group g, like GetAll. The map is keyed by the keys passed to Key.
+func GetGroup(r *http.Request, g *KeyGroup) map[interface{}]interface{} {
+	result := make(map[interface{}]interface{})
+	all, _ := storedValues(r, false)
+	for k, v := range all {
+		if gk, ok := k.(groupKey); ok && gk.group == g.name {
+			result[gk.key] = v
+		}
+	}
+	return result
+}
@@ -0,0 +1,35 @@
+package context
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGroup(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	auth, cache := Group("auth"), Group("cache")
+	Set(r, "user", "app")
+	Set(r, auth.Key("user"), "gopher")
+	Set(r, Group("auth").Key("scopes"), "read")
+	Set(r, cache.Key("max-age"), 60)
+
+	if Get(r, "user") != "app" || Get(r, auth.Key("user")) != "gopher" {
+		t.Errorf("Expected group keys to be distinct, got %v", GetAll(r))
+	}
+	if g := GetGroup(r, auth); len(g) != 2 || g["user"] != "gopher" || g["scopes"] != "read" {
+		t.Errorf("Unexpected auth values %v", g)
+	}
+	if d := Dump(r); !strings.Contains(d, "cache:max-age (context.groupKey) = 60") {
+		t.Errorf("Expected Dump to name the group, got %q", d)
+	}
+
+	ClearGroup(r, auth)
+	if g := GetGroup(r, auth); len(g) != 0 {
+		t.Errorf("Expected the auth values to be removed, got %v", g)
+	}
+	if Get(r, "user") != "app" || Get(r, cache.Key("max-age")) != 60 {
+		t.Error("Expected other values to be kept")
+	}
+}
@@ -0,0 +1,147 @@
+package context
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// HeaderCodec converts values to header values and back.
+type HeaderCodec interface {
+	Encode(v interface{}) (string, error)
+	Decode(s string) (interface{}, error)
+}
+
+// HeaderMapping sends the value stored under a string key in a header.
+type HeaderMapping struct {
+	Key    string
+	Header string
+	// Codec converts the value. If nil, StringCodec is used.
+	Codec HeaderCodec
+}
+
+// StringCodec formats values with fmt.Sprint, and decodes them as strings.
+var StringCodec HeaderCodec = stringCodec{}
+
+// JSONCodec encodes values as base64url-encoded JSON, so that any value
+// marshaled by encoding/json can travel in a header. Values are decoded as
+// by json.Unmarshal into an interface{}.
+var JSONCodec HeaderCodec = jsonCodec{}
+
+type stringCodec struct{}
+
+func (stringCodec) Encode(v interface{}) (string, error) {
+	return fmt.Sprint(v), nil
+}
+
+func (stringCodec) Decode(s string) (interface{}, error) {
+	return s, nil
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (jsonCodec) Decode(s string) (interface{}, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	err = json.Unmarshal(b, &v)
+	return v, err
+}
+
+// headerMappings holds the mappings set with SetHeaderMappings, by key.
+var headerMappings map[string]HeaderMapping
+
+// SetHeaderMappings sets the headers used by Inject and Extract, replacing
+// the previous mappings. It is meant to be called during program
+// initialization:
+//
+//	context.SetHeaderMappings(
+//		context.HeaderMapping{Key: "tenant", Header: "X-Tenant"},
+//		context.HeaderMapping{Key: "user", Header: "X-User", Codec: context.JSONCodec},
+//	)
+func SetHeaderMappings(mappings ...HeaderMapping) {
+	m := make(map[string]HeaderMapping, len(mappings))
+	for _, hm := range mappings {
+		if hm.Codec == nil {
+			hm.Codec = StringCodec
+		}
+		m[hm.Key] = hm
+	}
+	mutex.Lock()
+	headerMappings = m
+	mutex.Unlock()
+}
+
+func currentHeaderMappings() map[string]HeaderMapping {
+	mutex.RLock()
+	m := headerMappings
+	mutex.RUnlock()
+	return m
+}
+
+// Inject sets the headers mapped to keys in out, usually the headers of an
+// outgoing request, to the values stored for r. If no keys are given, all
+// mapped keys are used. Keys without a mapping or a value are skipped.
+//
+// It returns the first encoding error, after setting the other headers.
+func Inject(r *http.Request, out http.Header, keys ...string) error {
+	mappings := currentHeaderMappings()
+	if len(keys) == 0 {
+		for k := range mappings {
+			keys = append(keys, k)
+		}
+	}
+	var first error
+	for _, k := range keys {
+		hm, ok := mappings[k]
+		if !ok {
+			continue
+		}
+		v, ok := GetOk(r, k)
+		if !ok {
+			continue
+		}
+		s, err := hm.Codec.Encode(v)
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+		out.Set(hm.Header, s)
+	}
+	return first
+}
+
+// Extract stores in r the values of the mapped headers present in in,
+// usually the headers of r itself, under their keys.
+//
+// It returns the first decoding error, after storing the other values.
+func Extract(r *http.Request, in http.Header) error {
+	var first error
+	for _, hm := range currentHeaderMappings() {
+		s := in.Get(hm.Header)
+		if s == "" {
+			continue
+		}
+		v, err := hm.Codec.Decode(s)
+		if err == nil {
+			err = SetE(r, hm.Key, v)
+		}
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
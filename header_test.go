@@ -0,0 +1,54 @@
+package context
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestInjectExtract(t *testing.T) {
+	SetHeaderMappings(
+		HeaderMapping{Key: "tenant", Header: "X-Tenant"},
+		HeaderMapping{Key: "user", Header: "X-User", Codec: JSONCodec},
+		HeaderMapping{Key: "missing", Header: "X-Missing"},
+	)
+	defer SetHeaderMappings()
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	Set(r, "tenant", 42)
+	Set(r, "user", map[string]interface{}{"name": "gopher"})
+	Set(r, "unmapped", "x")
+
+	out := http.Header{}
+	if err := Inject(r, out, "tenant", "unmapped"); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 1 || out.Get("X-Tenant") != "42" {
+		t.Errorf("Unexpected headers %v", out)
+	}
+	out = http.Header{}
+	if err := Inject(r, out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 2 || out.Get("X-User") == "" {
+		t.Errorf("Unexpected headers %v", out)
+	}
+
+	in, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(in)
+	in.Header = out
+	if err := Extract(in, in.Header); err != nil {
+		t.Fatal(err)
+	}
+	if v := Get(in, "tenant"); v != "42" {
+		t.Errorf("Expected the tenant, got %#v", v)
+	}
+	if v, ok := Get(in, "user").(map[string]interface{}); !ok || v["name"] != "gopher" {
+		t.Errorf("Expected the user, got %#v", Get(in, "user"))
+	}
+
+	in.Header.Set("X-User", "!")
+	if err := Extract(in, in.Header); err == nil {
+		t.Error("Expected a decoding error")
+	}
+}
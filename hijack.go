@@ -0,0 +1,56 @@
+package context
+
+import (
+	"net"
+	"net/http"
+	"sync"
+)
+
+// TransferToConn ties the values of a request to a connection taken over
+// with http.Hijacker, for WebSocket upgrades or CONNECT tunnels, whose
+// lifetime exceeds the handler's. ClearHandler no longer clears the request
+// when the handler returns; instead it is cleared when the returned
+// connection is closed, which the caller must use in place of conn.
+//
+//	conn, _, err := w.(http.Hijacker).Hijack()
+//	if err != nil {
+//		// ...
+//	}
+//	conn = context.TransferToConn(r, conn)
+//	go serveTunnel(r, conn)
+//
+// Clear and Purge still clear the request explicitly.
+func TransferToConn(r *http.Request, conn net.Conn) net.Conn {
+	s := attach(r)
+	s.detached = true
+	s.mu.Unlock()
+	return &hijackedConn{Conn: conn, r: r}
+}
+
+// isDetached reports whether the values of r were transferred to a
+// connection.
+func isDetached(r *http.Request) bool {
+	s := lookup(r)
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	detached := s.detached && s.ownedBy(r)
+	s.mu.RUnlock()
+	return detached
+}
+
+// hijackedConn clears a request when closed.
+type hijackedConn struct {
+	net.Conn
+	r    *http.Request
+	once sync.Once
+}
+
+func (c *hijackedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(func() {
+		Clear(c.r)
+	})
+	return err
+}
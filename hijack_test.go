@@ -0,0 +1,39 @@
+package context
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTransferToConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	var conn net.Conn
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	h := ClearHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Set(r, key1, "1")
+		conn = TransferToConn(r, server)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	if v := Get(r, key1); v != "1" {
+		t.Fatalf("Expected the value to outlive the handler, got %v", v)
+	}
+	conn.Close()
+	if _, ok := GetAllOk(r); ok {
+		t.Error("Expected the request to be cleared with the connection")
+	}
+	conn.Close()
+
+	// A new store for the same request is cleared by ClearHandler again.
+	h = ClearHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Set(r, key1, "1")
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if _, ok := GetAllOk(r); ok {
+		t.Error("Expected the request to be cleared by ClearHandler")
+	}
+}
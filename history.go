@@ -0,0 +1,120 @@
+package context
+
+import (
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Mutation is a change of a value recorded by History.
+type Mutation struct {
+	// Deleted is true for a Delete, and false for a Set.
+	Deleted bool
+	// Time is the time of the change.
+	Time time.Time
+	// Stack is the call stack of the change, outside this package.
+	Stack string
+}
+
+// history is read by every Set, so it is accessed atomically instead of
+// under the package lock.
+var history int64
+
+// EnableHistory turns the recording of changes, for History, on or off. It
+// is meant for development: recording the call stack of every change is
+// slow.
+func EnableHistory(on bool) {
+	if on {
+		atomic.StoreInt64(&history, 1)
+	} else {
+		atomic.StoreInt64(&history, 0)
+	}
+}
+
+// History returns the changes of the value stored for key in a request, by
+// Set, SetE, SetS, Key.Set, Delete and Key.Delete, oldest first. Changes
+// are only recorded while EnableHistory is on, and are dropped when the
+// request is cleared.
+func History(r *http.Request, key interface{}) []Mutation {
+	s := lookup(r)
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.ownedBy(r) {
+		return nil
+	}
+	return append([]Mutation(nil), s.history[key]...)
+}
+
+// recordSet records a Set if history is enabled.
+func recordSet(r *http.Request, key interface{}) {
+	if loadInt(&history) != 0 {
+		record(r, key, false)
+	}
+}
+
+// recordSetS is recordSet for string keys, only converting the key to
+// interface{} if needed.
+func recordSetS(r *http.Request, key string) {
+	if loadInt(&history) != 0 {
+		record(r, key, false)
+	}
+}
+
+// recordDelete records a Delete if history is enabled.
+func recordDelete(r *http.Request, key interface{}) {
+	if loadInt(&history) != 0 {
+		record(r, key, true)
+	}
+}
+
+func record(r *http.Request, key interface{}, deleted bool) {
+	m := Mutation{Deleted: deleted, Time: now(), Stack: stack()}
+	var s *Store
+	if deleted {
+		if s = writeLocked(r); s == nil {
+			return
+		}
+	} else {
+		s = attach(r)
+	}
+	s.addMutation(key, m)
+	s.mu.Unlock()
+}
+
+// recordLocked records a change of key if history is enabled. It must be
+// called with the store locked for writing.
+func (s *Store) recordLocked(key interface{}, deleted bool) {
+	if loadInt(&history) != 0 {
+		s.addMutation(key, Mutation{Deleted: deleted, Time: now(), Stack: stack()})
+	}
+}
+
+func (s *Store) addMutation(key interface{}, m Mutation) {
+	if s.history == nil {
+		s.history = make(map[interface{}][]Mutation)
+	}
+	s.history[key] = append(s.history[key], m)
+}
+
+// stack returns the call stack outside this package, formatted like
+// runtime/debug.Stack. Tests of the package count as outside.
+func stack() string {
+	var pcs [32]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs[:])])
+	var b strings.Builder
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, pkgPrefix) || strings.HasSuffix(f.File, "_test.go") {
+			b.WriteString(f.Function + "()\n\t" + f.File + ":" + strconv.Itoa(f.Line) + "\n")
+		}
+		if !more {
+			return b.String()
+		}
+	}
+}
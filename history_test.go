@@ -0,0 +1,57 @@
+package context
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+
+	Set(r, key1, "unrecorded")
+	EnableHistory(true)
+	defer EnableHistory(false)
+	Set(r, key1, "1")
+	SetS(r, "s", 1)
+	Delete(r, key1)
+
+	h := History(r, key1)
+	if len(h) != 2 || h[0].Deleted || !h[1].Deleted {
+		t.Fatalf("Unexpected history %+v", h)
+	}
+	if !strings.Contains(h[0].Stack, "TestHistory") || strings.Contains(h[0].Stack, "recordSet") {
+		t.Errorf("Unexpected stack %s", h[0].Stack)
+	}
+	if len(History(r, "s")) != 1 {
+		t.Error("Expected SetS to be recorded")
+	}
+
+	Clear(r)
+	if h := History(r, key1); h != nil {
+		t.Errorf("Expected the history to be cleared, got %+v", h)
+	}
+}
+
+func TestHistoryFrozen(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+
+	EnableHistory(true)
+	defer EnableHistory(false)
+	Set(r, key1, "1")
+	Freeze(r)
+	Set(r, key1, "2")
+	SetS(r, "s", 1)
+	NewKey[int]("typed").Set(r, 1)
+	Delete(r, key1)
+	Pop(r, key1)
+
+	if h := History(r, key1); len(h) != 1 || h[0].Deleted {
+		t.Errorf("Expected only the set before Freeze, got %+v", h)
+	}
+	if h := History(r, "s"); h != nil {
+		t.Errorf("Expected refused sets not to be recorded, got %+v", h)
+	}
+}
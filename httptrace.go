@@ -0,0 +1,129 @@
+package context
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// ClientTrace holds the timings of an outgoing request, as recorded by
+// TraceClient.
+type ClientTrace struct {
+	// Method and URL identify the outgoing request.
+	Method string
+	URL    string
+	// Reused reports whether the connection was reused.
+	Reused bool
+	// DNS, Connect and TLS are the durations of the DNS lookup, connection
+	// and TLS handshake. They are 0 if the steps didn't happen, for example
+	// because the connection was reused.
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	// FirstByte is the time from the start of the request to the first
+	// byte of the response.
+	FirstByte time.Duration
+}
+
+// TraceClient returns a copy of an outgoing request out that records its
+// timings in parent, the request being served, once the first response
+// byte is received. They are reported by ClientTraces, so that they can be
+// logged at the end of the parent request, and dropped if it was cleared
+// before:
+//
+//	out, _ := http.NewRequestWithContext(r.Context(), "GET", url, nil)
+//	resp, err := client.Do(context.TraceClient(r, out))
+func TraceClient(parent, out *http.Request) *http.Request {
+	// Make sure parent has a store while it is served: record only uses an
+	// existing one.
+	attach(parent).mu.Unlock()
+	t := &clientTracer{
+		parent: parent,
+		trace:  ClientTrace{Method: out.Method, URL: out.URL.String()},
+	}
+	ct := &httptrace.ClientTrace{
+		GetConn: func(string) {
+			t.mu.Lock()
+			t.start = now()
+			t.mu.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.trace.Reused = info.Reused
+			t.mu.Unlock()
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.begin(&t.dns)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.end(&t.trace.DNS, &t.dns)
+		},
+		ConnectStart: func(string, string) {
+			t.begin(&t.connect)
+		},
+		ConnectDone: func(string, string, error) {
+			t.end(&t.trace.Connect, &t.connect)
+		},
+		TLSHandshakeStart: func() {
+			t.begin(&t.tls)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.end(&t.trace.TLS, &t.tls)
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			t.trace.FirstByte = since(t.start)
+			trace := t.trace
+			t.mu.Unlock()
+			t.record(trace)
+		},
+	}
+	return out.WithContext(httptrace.WithClientTrace(out.Context(), ct))
+}
+
+// ClientTraces returns the timings recorded for the outgoing requests of a
+// request by TraceClient, in the order they were received.
+func ClientTraces(r *http.Request) []ClientTrace {
+	traces, _ := Get(r, clientTraceKey).([]ClientTrace)
+	return traces
+}
+
+// clientTracer collects the timings of an outgoing request. Trace hooks may
+// be called from several goroutines.
+type clientTracer struct {
+	parent *http.Request
+	mu     sync.Mutex
+	trace  ClientTrace
+	start  time.Time
+	// dns, connect and tls are the start times of the steps.
+	dns, connect, tls time.Time
+}
+
+func (t *clientTracer) begin(start *time.Time) {
+	t.mu.Lock()
+	*start = now()
+	t.mu.Unlock()
+}
+
+func (t *clientTracer) end(d *time.Duration, start *time.Time) {
+	t.mu.Lock()
+	*d = since(*start)
+	t.mu.Unlock()
+}
+
+// record appends a trace to those of the parent request. The stored slice
+// is never modified, so callers of ClientTraces can keep theirs. The trace
+// is dropped if the parent was cleared meanwhile: storing it would create a
+// store nobody clears.
+func (t *clientTracer) record(trace ClientTrace) {
+	s := writeLocked(t.parent)
+	if s == nil {
+		return
+	}
+	defer s.mu.Unlock()
+	l := lockedStore{t.parent, s}
+	traces, _ := l.Get(clientTraceKey).([]ClientTrace)
+	l.Set(clientTraceKey, append(traces[:len(traces):len(traces)], trace))
+}
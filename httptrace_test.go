@@ -0,0 +1,60 @@
+package context
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTraceClient(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer backend.Close()
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	for i := 0; i < 2; i++ {
+		out, _ := http.NewRequest("GET", backend.URL+"/sub", nil)
+		resp, err := backend.Client().Do(TraceClient(r, out))
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	traces := ClientTraces(r)
+	if len(traces) != 2 {
+		t.Fatalf("Expected 2 traces, got %+v", traces)
+	}
+	if tr := traces[0]; tr.URL != backend.URL+"/sub" || tr.Method != "GET" || tr.Reused || tr.Connect <= 0 || tr.FirstByte <= 0 {
+		t.Errorf("Unexpected first trace %+v", tr)
+	}
+	if tr := traces[1]; !tr.Reused || tr.Connect != 0 {
+		t.Errorf("Expected the connection to be reused, got %+v", tr)
+	}
+}
+
+func TestTraceClientCleared(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer backend.Close()
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	out, _ := http.NewRequest("GET", backend.URL+"/background", nil)
+	out = TraceClient(r, out)
+	Clear(r)
+	resp, err := backend.Client().Do(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if lookup(r) != nil {
+		Clear(r)
+		t.Error("Expected the trace of a cleared request to be dropped")
+	}
+}
@@ -0,0 +1,32 @@
+package context
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestInternal(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	Set(r, key1, "1")
+	SetTenant(r, "acme")
+	SetUser(r, "gopher")
+	if all := GetAll(r); len(all) != 1 || all[key1] != "1" {
+		t.Errorf("Expected GetAll to leave internal values out, got %v", all)
+	}
+	in := Internal(r)
+	if len(in) != 2 || in["tenant"] != "acme" || in["user"] != "gopher" {
+		t.Errorf("Unexpected internal values %v", in)
+	}
+
+	// Internal values hold objects of the request, such as its cache: they
+	// are not shared with others.
+	r2, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r2)
+	if err := Transfer(r2, r); err != nil {
+		t.Fatal(err)
+	}
+	if Tenant(r2) != "" || Get(r2, key1) != "1" {
+		t.Error("Expected Transfer to copy only the values of the application")
+	}
+}
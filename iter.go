@@ -0,0 +1,27 @@
+//go:build go1.23
+
+package context
+
+import (
+	"iter"
+	"net/http"
+)
+
+// All returns an iterator over the values stored for a given request, in no
+// particular order:
+//
+//	for k, v := range context.All(r) {
+//		log.Println(k, v)
+//	}
+//
+// It iterates over the values of GetAll, copied when the iteration starts:
+// the loop may change the values of the request.
+func All(r *http.Request) iter.Seq2[interface{}, interface{}] {
+	return func(yield func(interface{}, interface{}) bool) {
+		for k, v := range GetAll(r) {
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,37 @@
+//go:build go1.23
+
+package context
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAll(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	Set(r, key1, "1")
+	SetS(r, "user", "gopher")
+
+	got := make(map[interface{}]interface{})
+	for k, v := range All(r) {
+		got[k] = v
+		Delete(r, k)
+		Set(r, key2, "added")
+	}
+	if len(got) != 2 || got[key1] != "1" || got["user"] != "gopher" {
+		t.Errorf("Unexpected values %v", got)
+	}
+	if all := GetAll(r); len(all) != 1 || all[key2] != "added" {
+		t.Errorf("Expected the loop changes to be kept, got %v", all)
+	}
+
+	n := 0
+	for range All(r) {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Errorf("Expected the loop to stop, got %d values", n)
+	}
+}
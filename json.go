@@ -0,0 +1,48 @@
+package context
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// MarshalJSON returns the values stored for a request with string keys as
+// a JSON object sorted by key, for logs and error reports. Values with other
+// keys, and values that can't be marshaled, are left out. Secret values are
+// redacted.
+func MarshalJSON(r *http.Request) ([]byte, error) {
+	values := make(map[string]json.RawMessage)
+	for k, v := range GetAll(r) {
+		name, ok := k.(string)
+		if !ok {
+			continue
+		}
+		b, err := json.Marshal(Redact(k, v))
+		if err != nil {
+			continue
+		}
+		values[name] = b
+	}
+	return json.Marshal(values)
+}
+
+// UnmarshalInto stores the members of a JSON object in a request, as
+// produced by MarshalJSON, with their names as keys. Values are decoded as
+// by json.Unmarshal into an interface{}: numbers become float64, objects
+// map[string]interface{} and so on.
+//
+// Nothing is stored if data is not a valid JSON object.
+func UnmarshalInto(r *http.Request, data []byte) error {
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	var first error
+	WithStore(r, func(s MutableStore) {
+		for k, v := range values {
+			if err := s.SetE(k, v); err != nil && first == nil {
+				first = err
+			}
+		}
+	})
+	return first
+}
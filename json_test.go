@@ -0,0 +1,38 @@
+package context
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	Set(r, "user", "gopher")
+	Set(r, "roles", []string{"admin"})
+	Set(r, "func", func() {})
+	Set(r, key1, "untyped key")
+
+	b, err := MarshalJSON(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"roles":["admin"],"user":"gopher"}`; string(b) != want {
+		t.Errorf("Expected %s, got %s", want, b)
+	}
+
+	r2, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r2)
+	if err := UnmarshalInto(r2, b); err != nil {
+		t.Fatal(err)
+	}
+	if v := Get(r2, "user"); v != "gopher" {
+		t.Errorf("Expected gopher, got %v", v)
+	}
+	if v, ok := Get(r2, "roles").([]interface{}); !ok || len(v) != 1 || v[0] != "admin" {
+		t.Errorf("Unexpected roles %#v", Get(r2, "roles"))
+	}
+	if err := UnmarshalInto(r2, []byte("[1]")); err == nil {
+		t.Error("Expected an error for a non-object")
+	}
+}
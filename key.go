@@ -0,0 +1,142 @@
+package context
+
+import (
+	"net/http"
+)
+
+// Key is a typed key. Values stored with a Key are also visible to Get,
+// GetAll and the other untyped functions, using the Key pointer as the key.
+//
+// Key keeps its value in a cell allocated on the first Set and updated in
+// place afterwards, so that getting and setting values such as ints doesn't
+// convert them to interface{} and allocate on every call.
+type Key[T any] struct {
+	name string
+}
+
+// NewKey returns a new typed key. The name is only used for debugging.
+func NewKey[T any](name string) *Key[T] {
+	return &Key[T]{name: name}
+}
+
+// String returns the name of the key.
+func (k *Key[T]) String() string {
+	return k.name
+}
+
+// cell holds the value of a Key in a store.
+type cell[T any] struct {
+	v T
+}
+
+func (c *cell[T]) unbox() interface{} {
+	return c.v
+}
+
+// copyBox returns a new cell, for Transfer: cells are updated in place.
+func (c *cell[T]) copyBox() interface{} {
+	return &cell[T]{v: c.v}
+}
+
+// Set stores a value for the key in a given request.
+func (k *Key[T]) Set(r *http.Request, val T) {
+	_ = k.SetE(r, val)
+}
+
+// SetE stores a value for the key in a given request, like SetE.
+func (k *Key[T]) SetE(r *http.Request, val T) error {
+	traceSet(r, k)
+	if b := recorder.Load(); b != nil {
+		// Only converted to interface{} when recording.
+		b.add(Op{Kind: OpSet, Request: r, Key: k, Value: val})
+	}
+	s := attach(r)
+	if s.stripes == nil && s.snap.Load() == nil && !s.frozen && s.layers == nil && s.watchers == nil && plainWrites() {
+		// Readers hold the store lock, so the cell can be updated in place.
+		if raw, ok := s.values.getRaw(k); ok {
+			if c, ok := raw.(*cell[T]); ok {
+				c.v = val
+				s.mu.Unlock()
+				recordSet(r, k)
+				return nil
+			}
+		}
+	}
+	err := s.set(k, &cell[T]{v: val})
+	s.mu.Unlock()
+	if err == nil {
+		recordSet(r, k)
+	}
+	return checkFrozen(err)
+}
+
+// Get returns the value stored for the key in a given request, or the zero
+// value of T if there is none.
+func (k *Key[T]) Get(r *http.Request) T {
+	v, _ := k.GetOk(r)
+	return v
+}
+
+// GetOk returns the value stored for the key in a given request and whether
+// it was present.
+func (k *Key[T]) GetOk(r *http.Request) (T, bool) {
+	traceGet(r, k)
+	observe(OpGet, r, k, nil)
+	v, ok := k.getOk(r)
+	for p := r; !ok; {
+		if p = parentFor(p, k); p == nil {
+			break
+		}
+		v, ok = k.getOk(p)
+	}
+	if !ok {
+		v, ok = fromRaw[T](getDefault(k))
+	}
+	countGet(k, ok)
+	return v, ok
+}
+
+func (k *Key[T]) getOk(r *http.Request) (T, bool) {
+	var zero T
+	s := lookup(r)
+	if s == nil {
+		return zero, false
+	}
+	if snap := s.snap.Load(); snap != nil {
+		if !snap.ownedBy(r) {
+			return zero, false
+		}
+		raw, ok := snap.values.getRaw(k)
+		return fromRaw[T](raw, ok)
+	}
+	s.mu.RLock()
+	if !s.ownedBy(r) {
+		s.mu.RUnlock()
+		return zero, false
+	}
+	raw, ok := s.getRawKey(k)
+	if ok && trackUse() {
+		s.touch(k)
+	}
+	s.mu.RUnlock()
+	return fromRaw[T](raw, ok)
+}
+
+// Delete removes the value stored for the key in a given request.
+func (k *Key[T]) Delete(r *http.Request) {
+	Delete(r, k)
+}
+
+// fromRaw returns the value of a raw stored value. Values stored under a Key
+// with Set rather than Key.Set are not in a cell.
+func fromRaw[T any](raw interface{}, ok bool) (T, bool) {
+	switch v := raw.(type) {
+	case *cell[T]:
+		return v.v, ok
+	case T:
+		return v, ok
+	}
+	var zero T
+	// A nil value stored with Set is the zero value of interface types.
+	return zero, ok && raw == nil
+}
@@ -0,0 +1,100 @@
+package context
+
+import (
+	"net/http"
+	"testing"
+)
+
+var (
+	countKey = NewKey[int]("count")
+	userKey  = NewKey[*struct{ Name string }]("user")
+)
+
+func TestKey(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+
+	if v, ok := countKey.GetOk(r); ok || v != 0 {
+		t.Errorf("Expected (0, false), got (%v, %v)", v, ok)
+	}
+	countKey.Set(r, 1000)
+	countKey.Set(r, countKey.Get(r)+1)
+	if v := countKey.Get(r); v != 1001 {
+		t.Errorf("Expected 1001, got %v", v)
+	}
+	// Typed values are visible to the untyped API.
+	if v := Get(r, countKey); v != 1001 {
+		t.Errorf("Expected 1001, got %v", v)
+	}
+	if all := GetAll(r); all[countKey] != 1001 {
+		t.Errorf("Expected 1001, got %v", all[countKey])
+	}
+	// And values stored with the untyped API are visible to the key.
+	Set(r, countKey, 7)
+	if v := countKey.Get(r); v != 7 {
+		t.Errorf("Expected 7, got %v", v)
+	}
+	Set(r, countKey, "not an int")
+	if v, ok := countKey.GetOk(r); ok || v != 0 {
+		t.Errorf("Expected (0, false) for a value of another type, got (%v, %v)", v, ok)
+	}
+
+	if u := userKey.Get(r); u != nil {
+		t.Errorf("Expected nil, got %v", u)
+	}
+	userKey.Set(r, &struct{ Name string }{"gorilla"})
+	if u := userKey.Get(r); u == nil || u.Name != "gorilla" {
+		t.Errorf("Expected gorilla, got %v", u)
+	}
+	userKey.Delete(r)
+	if _, ok := userKey.GetOk(r); ok {
+		t.Error("Expected user to be deleted")
+	}
+}
+
+func TestKeyCopyOnWrite(t *testing.T) {
+	SetCopyOnWrite(true)
+	defer SetCopyOnWrite(false)
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+
+	countKey.Set(r, 1)
+	before := GetAll(r)
+	countKey.Set(r, 2)
+	if before[countKey] != 1 || countKey.Get(r) != 2 {
+		t.Errorf("Expected snapshots to be immutable, got %v and %v", before[countKey], countKey.Get(r))
+	}
+}
+
+func TestKeyNoAlloc(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+
+	countKey.Set(r, 0)
+	allocs := testing.AllocsPerRun(100, func() {
+		countKey.Set(r, countKey.Get(r)+1000)
+	})
+	if allocs != 0 {
+		t.Errorf("Expected no allocations, got %v", allocs)
+	}
+}
+
+func BenchmarkKeyGetSet(b *testing.B) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		countKey.Set(r, countKey.Get(r)+1000)
+	}
+}
+
+func BenchmarkUntypedGetSet(b *testing.B) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	Set(r, key1, 0)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Set(r, key1, Get(r, key1).(int)+1000)
+	}
+}
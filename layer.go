@@ -0,0 +1,127 @@
+package context
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// layered counts the layers pushed and not popped, so that ClearHandler only
+// looks for leaked layers when there may be some.
+var layered int64
+
+// layer holds the values a layer replaced, to be restored by PopLayer.
+type layer map[interface{}]saved
+
+// saved is a raw value replaced in a layer, or its absence.
+type saved struct {
+	val interface{}
+	ok  bool
+}
+
+// PushLayer starts a layer of changes to the values of a request: the
+// values set or deleted until the matching PopLayer are restored by it.
+// Layers can be nested. A middleware impersonating another user for a
+// sub-call does:
+//
+//	context.PushLayer(r)
+//	context.SetUser(r, other)
+//	h.ServeHTTP(w, r)
+//	context.PopLayer(r)
+//
+// ClearHandler pops the layers left pushed, so that deferred functions and
+// listeners see the original values.
+func PushLayer(r *http.Request) {
+	s := attach(r)
+	s.layers = append(s.layers, nil)
+	s.mu.Unlock()
+	atomic.AddInt64(&layered, 1)
+}
+
+// PopLayer restores the values changed since the last PushLayer. It does
+// nothing if no layer was pushed.
+func PopLayer(r *http.Request) {
+	if s := writeLocked(r); s != nil {
+		s.popLayer()
+		s.mu.Unlock()
+	}
+}
+
+// popLayers pops all the layers of a request.
+func popLayers(r *http.Request) {
+	if loadInt(&layered) == 0 {
+		return
+	}
+	if s := writeLocked(r); s != nil {
+		for s.popLayer() {
+		}
+		s.mu.Unlock()
+	}
+}
+
+// popLayer restores the values of the top layer, and reports whether there
+// was one. It must be called with the store locked for writing.
+func (s *Store) popLayer() bool {
+	n := len(s.layers)
+	if n == 0 {
+		return false
+	}
+	top := s.layers[n-1]
+	// Restore without recording the changes in the layer below.
+	below := s.layers[:n-1]
+	s.layers = nil
+	for k, v := range top {
+		s.restore(k, v)
+	}
+	if len(below) > 0 {
+		s.layers = below
+	}
+	atomic.AddInt64(&layered, -1)
+	return true
+}
+
+// restore puts back a value saved by saveForLayer. It bypasses the checks
+// of set and remove: the value was stored before, and restoring it must not
+// fail because the request was frozen or a limit was reached meanwhile. It
+// must be called with the store locked for writing.
+func (s *Store) restore(key interface{}, v saved) {
+	if size, ok := s.sizes[key]; ok {
+		addMemory(-size)
+		delete(s.sizes, key)
+	}
+	if !v.ok {
+		s.delKey(key)
+		delete(s.used, key)
+	} else {
+		s.putKey(key, v.val)
+		if trackUse() {
+			s.touchNew(key)
+		}
+		if loadInt(&memLimit) > 0 && !isInternal(key) {
+			size := sizeOf(v.val)
+			addMemory(size)
+			if s.sizes == nil {
+				s.sizes = make(map[interface{}]int)
+			}
+			s.sizes[key] = size
+		}
+	}
+	s.publish()
+	if s.watchers != nil {
+		s.notifyWatchers(key, unbox(v.val))
+	}
+}
+
+// saveForLayer records the current value of key in the top layer, unless
+// it was already recorded. It must be called with the store locked for
+// writing, before changing the value.
+func (s *Store) saveForLayer(key interface{}) {
+	top := &s.layers[len(s.layers)-1]
+	if _, ok := (*top)[key]; ok {
+		return
+	}
+	if *top == nil {
+		*top = make(layer)
+	}
+	v, ok := s.getRawKey(key)
+	(*top)[key] = saved{v, ok}
+}
@@ -0,0 +1,95 @@
+package context
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLayers(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	k := NewKey[int]("count")
+	SetUser(r, "gopher")
+	Set(r, key1, "1")
+	k.Set(r, 1)
+
+	PushLayer(r)
+	SetUser(r, "admin")
+	Delete(r, key1)
+	k.Set(r, 2)
+	PushLayer(r)
+	Set(r, key2, "2")
+	SetUser(r, "root")
+	k.Set(r, 3)
+	PopLayer(r)
+	if User(r) != "admin" || k.Get(r) != 2 {
+		t.Errorf("Expected the inner layer to be restored, got %v and %d", User(r), k.Get(r))
+	}
+	if _, ok := GetOk(r, key2); ok {
+		t.Error("Expected values added in the layer to be removed")
+	}
+	PopLayer(r)
+	PopLayer(r)
+	if User(r) != "gopher" || Get(r, key1) != "1" || k.Get(r) != 1 {
+		t.Errorf("Expected the original values, got %v", Dump(r))
+	}
+}
+
+// clearedListener records the values of the cleared requests.
+type clearedListener struct {
+	values []map[interface{}]interface{}
+}
+
+func (l *clearedListener) OnStoreCreated(r *http.Request) {}
+
+func (l *clearedListener) OnCleared(r *http.Request, values map[interface{}]interface{}) {
+	l.values = append(l.values, values)
+}
+
+func (l *clearedListener) OnPurged(count int) {}
+
+func TestClearHandlerPopsLayers(t *testing.T) {
+	l := &clearedListener{}
+	AddListener(l)
+	defer func() {
+		mutex.Lock()
+		listeners = nil
+		mutex.Unlock()
+	}()
+
+	h := ClearHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetUser(r, "gopher")
+		PushLayer(r)
+		SetUser(r, "admin")
+		PushLayer(r)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if len(l.values) != 1 || l.values[0][principalKey] != "gopher" {
+		t.Errorf("Expected the original values to be cleared, got %v", l.values)
+	}
+	if n := atomic.LoadInt64(&layered); n != 0 {
+		t.Errorf("Expected no layers left, got %d", n)
+	}
+}
+
+func TestPopLayerUnchecked(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	Set(r, key1, "1")
+	Set(r, key2, "2")
+
+	PushLayer(r)
+	Delete(r, key1)
+	Set(r, key2, "changed")
+	SetMaxEntries(1)
+	SetEvictionPolicy(RejectNew)
+	defer SetMaxEntries(0)
+	defer SetEvictionPolicy(EvictLRU)
+	Freeze(r)
+	PopLayer(r)
+	if Get(r, key1) != "1" || Get(r, key2) != "2" {
+		t.Errorf("Expected PopLayer to restore frozen values past the limits, got %v", Dump(r))
+	}
+}
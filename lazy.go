@@ -0,0 +1,124 @@
+package context
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// lazyValue boxes the values set with SetLazy. The factory runs on the first
+// read of the value by load: unbox, used when iterating over values, doesn't
+// run it.
+type lazyValue struct {
+	once    sync.Once
+	factory func() (interface{}, error)
+	v       interface{}
+	err     error
+	done    atomic.Bool
+}
+
+// unbox returns the value if the factory ran, and nil otherwise.
+func (l *lazyValue) unbox() interface{} {
+	if !l.done.Load() {
+		return nil
+	}
+	return l.v
+}
+
+func (l *lazyValue) get() (interface{}, error) {
+	l.once.Do(func() {
+		l.v, l.err = l.factory()
+		l.factory = nil
+		l.done.Store(true)
+	})
+	return l.v, l.err
+}
+
+// SetLazy stores a value computed by factory the first time it is read, for
+// expensive lookups that most requests never need:
+//
+//	context.SetLazy(r, accountKey, func() (interface{}, error) {
+//		return db.Account(userID)
+//	})
+//
+// The factory runs at most once, even if the value is read concurrently:
+// other readers wait for it. Until then, the value is present for GetOk and
+// Has, but left out of GetAll, Range and the other functions iterating over
+// values, which don't run the factory. Reads return nil if the factory
+// failed; GetLazy also returns its error.
+//
+// The factory runs while the store of the request is locked, so it must not
+// use the package for the same request. Values stored with SetLazy are not
+// checked against the type registered with RegisterKey.
+func SetLazy(r *http.Request, key interface{}, factory func() (interface{}, error)) {
+	Set(r, key, &lazyValue{factory: factory})
+}
+
+// GetLazy returns the value stored for a given key in a given request, like
+// Get, along with the error of its factory if it was set with SetLazy. The
+// factory is run outside the store lock.
+func GetLazy(r *http.Request, key interface{}) (interface{}, error) {
+	var raw interface{}
+	if vs, s := view(r); vs != nil {
+		raw, _ = vs.getRaw(key)
+		if s != nil {
+			s.mu.RUnlock()
+		}
+	}
+	if l, ok := raw.(*lazyValue); ok {
+		return l.get()
+	}
+	return Get(r, key), nil
+}
+
+// Do returns the value stored for a given key in a given request, computing
+// it with fn and storing it if there is none. Concurrent calls for the same
+// key share a single call of fn, so that goroutines resolving the same user
+// record make one database query:
+//
+//	v, err := context.Do(r, userKey, func() (interface{}, error) {
+//		return db.User(id)
+//	})
+//
+// If fn fails, its error is returned to the callers waiting for it and
+// nothing is stored: the next call tries again. Do returns the error of
+// SetE, without calling fn, if the value can't be stored. As for SetLazy, fn
+// must not use the package for the same request.
+func Do(r *http.Request, key interface{}, fn func() (interface{}, error)) (interface{}, error) {
+	traceGet(r, key)
+	observe(OpGet, r, key, nil)
+	s := attach(r)
+	raw, ok := s.getRawKey(key)
+	if ok {
+		s.mu.Unlock()
+		if l, ok := raw.(*lazyValue); ok {
+			return l.get()
+		}
+		return unbox(raw), nil
+	}
+	l := &lazyValue{factory: fn}
+	err := s.set(key, l)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, checkFrozen(err)
+	}
+	recordSet(r, key)
+	v, err := l.get()
+	if err != nil {
+		if s := writeLocked(r); s != nil {
+			if raw, _ := s.getRawKey(key); raw == l {
+				// Only fails for frozen stores, which keep the value.
+				_ = s.remove(key)
+			}
+			s.mu.Unlock()
+		}
+	}
+	return v, err
+}
+
+// pending reports whether v is a value set with SetLazy whose factory didn't
+// run yet.
+func pending(v interface{}) bool {
+	l, ok := v.(*lazyValue)
+	return ok && !l.done.Load()
+}
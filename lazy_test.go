@@ -0,0 +1,182 @@
+package context
+
+import (
+	"errors"
+	"net/http"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSetLazy(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	var calls int64
+	SetLazy(r, key1, func() (interface{}, error) {
+		atomic.AddInt64(&calls, 1)
+		return "account", nil
+	})
+	if !Has(r, key1) || atomic.LoadInt64(&calls) != 0 {
+		t.Error("Expected the value to be present but not computed")
+	}
+	if d := Dump(r); d != "0 (context.keyType) = [lazy]" || atomic.LoadInt64(&calls) != 0 {
+		t.Errorf("Expected Dump not to compute the value, got %q", d)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v := Get(r, key1); v != "account" {
+				t.Errorf("Expected account, got %v", v)
+			}
+		}()
+	}
+	wg.Wait()
+	if v, err := GetLazy(r, key1); v != "account" || err != nil {
+		t.Errorf("Expected account, got %v, %v", v, err)
+	}
+	if n := atomic.LoadInt64(&calls); n != 1 {
+		t.Errorf("Expected the factory to run once, got %d", n)
+	}
+
+	errNotFound := errors.New("not found")
+	SetLazy(r, key2, func() (interface{}, error) {
+		return nil, errNotFound
+	})
+	if v, err := GetLazy(r, key2); v != nil || err != errNotFound {
+		t.Errorf("Expected the factory error, got %v, %v", v, err)
+	}
+	if v := Get(r, key2); v != nil {
+		t.Errorf("Expected nil, got %v", v)
+	}
+	if v, err := GetLazy(r, "user"); v != nil || err != nil {
+		t.Errorf("Expected no value, got %v, %v", v, err)
+	}
+}
+
+func TestSetLazyIteration(t *testing.T) {
+	l := &clearedListener{}
+	AddListener(l)
+	defer func() {
+		mutex.Lock()
+		listeners = nil
+		mutex.Unlock()
+	}()
+	factory := func() (interface{}, error) {
+		t.Error("Expected the factory not to run")
+		return "account", nil
+	}
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	SetLazy(r, key1, factory)
+	SetLazy(r, "lazy/prefixed", factory)
+	Set(r, key2, "2")
+
+	if all := GetAll(r); len(all) != 1 || all[key2] != "2" {
+		t.Errorf("Expected GetAll to leave the lazy values out, got %v", all)
+	}
+	if snap := Snapshot(r); len(snap) != 1 {
+		t.Errorf("Expected Snapshot to leave the lazy values out, got %v", snap)
+	}
+	Range(r, func(k, v interface{}) bool {
+		return true
+	})
+	if n := MemoryUsage(r); n != sizeOf("2") {
+		t.Errorf("Expected only the computed value to be counted, got %d", n)
+	}
+	for _, e := range debugValues(r) {
+		if e.Key == keyName(key1) && e.Type != "[lazy]" {
+			t.Errorf("Expected DebugHandler to report a lazy value, got %q", e.Type)
+		}
+	}
+	dst, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	if err := Transfer(dst, r, key1); err != nil || !Has(dst, key1) {
+		t.Errorf("Expected the lazy value to be transferred, got %v", err)
+	}
+	Clear(dst)
+	a := ForGoroutine(r)
+	DeletePrefix(r, "lazy/")
+	if Has(r, "lazy/prefixed") {
+		t.Error("Expected DeletePrefix to delete the lazy value")
+	}
+	SetMaxEntries(2)
+	Set(r, "new", 1)
+	SetMaxEntries(0)
+	if Has(r, key1) {
+		t.Error("Expected the lazy value to be evicted")
+	}
+	Clear(r)
+	if len(l.values) != 2 || len(l.values[1]) != 2 {
+		t.Errorf("Expected the listener to get the computed values only, got %v", l.values)
+	}
+
+	// Accessors compute lazy values on first read.
+	r, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	SetLazy(r, key1, func() (interface{}, error) {
+		return "account", nil
+	})
+	if v := ForGoroutine(r).Get(key1); v != "account" {
+		t.Errorf("Expected the accessor to compute the value, got %v", v)
+	}
+	if a.Get(key2) != "2" {
+		t.Error("Expected the accessor to see computed values")
+	}
+}
+
+func TestDo(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	var calls int64
+	release := make(chan struct{})
+	fetch := func() (interface{}, error) {
+		atomic.AddInt64(&calls, 1)
+		<-release
+		return "gopher", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := Do(r, key1, fetch); v != "gopher" || err != nil {
+				t.Errorf("Expected gopher, got %v, %v", v, err)
+			}
+		}()
+	}
+	for !Has(r, key1) {
+		runtime.Gosched()
+	}
+	close(release)
+	wg.Wait()
+	if n := atomic.LoadInt64(&calls); n != 1 {
+		t.Errorf("Expected one call, got %d", n)
+	}
+	if Get(r, key1) != "gopher" {
+		t.Error("Expected the result to be stored")
+	}
+
+	Set(r, key2, "stored")
+	if v, _ := Do(r, key2, fetch); v != "stored" {
+		t.Errorf("Expected the stored value, got %v", v)
+	}
+
+	errDB := errors.New("db")
+	if _, err := Do(r, "user", func() (interface{}, error) { return nil, errDB }); err != errDB {
+		t.Errorf("Expected the error of fn, got %v", err)
+	}
+	if Has(r, "user") {
+		t.Error("Expected failures not to be stored")
+	}
+	if v, err := Do(r, "user", func() (interface{}, error) { return "retried", nil }); v != "retried" || err != nil {
+		t.Errorf("Expected a new call after a failure, got %v, %v", v, err)
+	}
+
+	Freeze(r)
+	if _, err := Do(r, "other", fetch); err != ErrFrozen {
+		t.Errorf("Expected ErrFrozen, got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package context
+
+import (
+	"net/http"
+)
+
+var (
+	// listeners holds the listeners registered with AddListener. The slice
+	// is only appended to, so a copy taken under the lock can be used after
+	// it is released.
+	listeners []Listener
+)
+
+// Listener is notified of changes in the lifecycle of request stores. This
+// lets APM agents and metrics libraries observe the package.
+//
+// Methods are called synchronously, without any lock held, from the
+// goroutine that caused the event.
+type Listener interface {
+	// OnStoreCreated is called when the first value is stored for a request.
+	OnStoreCreated(r *http.Request)
+	// OnCleared is called by Clear with the values that were removed. The
+	// map is no longer used by the package. Values set with SetLazy and
+	// never computed are left out.
+	OnCleared(r *http.Request, values map[interface{}]interface{})
+	// OnPurged is called by Purge with the number of requests removed.
+	OnPurged(count int)
+}
+
+// AddListener registers a Listener. There is no way to remove it, so it is
+// meant to be called once during program initialization.
+func AddListener(l Listener) {
+	mutex.Lock()
+	listeners = append(listeners, l)
+	mutex.Unlock()
+}
+
+func currentListeners() []Listener {
+	mutex.RLock()
+	ls := listeners
+	mutex.RUnlock()
+	return ls
+}
+
+func notifyCreated(ls []Listener, r *http.Request) {
+	for _, l := range ls {
+		l.OnStoreCreated(r)
+	}
+}
+
+func notifyCleared(ls []Listener, r *http.Request, values map[interface{}]interface{}) {
+	if len(ls) == 0 {
+		return
+	}
+	for _, l := range ls {
+		l.OnCleared(r, values)
+	}
+}
+
+func notifyPurged(ls []Listener, count int) {
+	for _, l := range ls {
+		l.OnPurged(count)
+	}
+}
+
+// Defer registers a function to be called when the values of a given request
+// are cleared by Clear, ClearHandler or Purge. Like the defer statement,
+// functions run in last-in-first-out order, and all of them run even if one
+// panics.
+//
+// This lets middleware deep in the chain release request-scoped resources
+// at the end of the request without wrapping the handler itself.
+func Defer(r *http.Request, f func()) {
+	s := attach(r)
+	s.deferred = append(s.deferred, f)
+	s.mu.Unlock()
+}
+
+// runDeferred calls fns in reverse order. It must be called without the lock
+// held, so that deferred functions can use the package.
+func runDeferred(fns []func()) {
+	for _, f := range fns {
+		defer f()
+	}
+}
@@ -0,0 +1,80 @@
+package context
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestDefer(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+
+	var calls []int
+	Defer(r, func() { calls = append(calls, 1) })
+	Defer(r, func() {
+		// The package can be used from a deferred function.
+		Set(r, key1, "1")
+		calls = append(calls, 2)
+	})
+	Clear(r)
+	if !reflect.DeepEqual(calls, []int{2, 1}) {
+		t.Errorf("Expected deferred calls [2 1], got %v", calls)
+	}
+	Clear(r)
+	if len(calls) != 2 {
+		t.Errorf("Expected deferred functions to run once, got %v", calls)
+	}
+
+	calls = nil
+	Defer(r, func() { calls = append(calls, 3) })
+	Purge(0)
+	if !reflect.DeepEqual(calls, []int{3}) {
+		t.Errorf("Expected Purge to run deferred functions, got %v", calls)
+	}
+}
+
+type recordingListener struct {
+	created []*http.Request
+	cleared []int
+	purged  []int
+}
+
+func (l *recordingListener) OnStoreCreated(r *http.Request) {
+	l.created = append(l.created, r)
+}
+
+func (l *recordingListener) OnCleared(r *http.Request, values map[interface{}]interface{}) {
+	l.cleared = append(l.cleared, len(values))
+}
+
+func (l *recordingListener) OnPurged(count int) {
+	l.purged = append(l.purged, count)
+}
+
+func TestListener(t *testing.T) {
+	l := &recordingListener{}
+	AddListener(l)
+	defer func() {
+		mutex.Lock()
+		listeners = nil
+		mutex.Unlock()
+	}()
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	Set(r, key1, "1")
+	Set(r, key2, "2")
+	if len(l.created) != 1 || l.created[0] != r {
+		t.Errorf("Expected one OnStoreCreated call, got %v", l.created)
+	}
+	Clear(r)
+	Clear(r)
+	if !reflect.DeepEqual(l.cleared, []int{2}) {
+		t.Errorf("Expected one OnCleared call with 2 values, got %v", l.cleared)
+	}
+
+	Set(r, key1, "1")
+	Purge(0)
+	if !reflect.DeepEqual(l.purged, []int{1}) {
+		t.Errorf("Expected one OnPurged call with 1 request, got %v", l.purged)
+	}
+}
@@ -0,0 +1,124 @@
+package context
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrMaxEntries is returned by SetE when a request already holds the maximum
+// number of entries and the RejectNew policy is in effect.
+var ErrMaxEntries = errors.New("context: maximum number of entries reached")
+
+// EvictionPolicy decides what happens when a new key is stored in a request
+// that already holds the number of entries configured with SetMaxEntries.
+type EvictionPolicy int
+
+const (
+	// EvictLRU removes the least recently used entry to make room for the
+	// new one. This is the default.
+	EvictLRU EvictionPolicy = iota
+	// RejectNew keeps the existing entries and refuses the new one.
+	RejectNew
+)
+
+var (
+	// maxEntries and evictPolicy are read by every Set, so they are accessed
+	// atomically instead of under the package lock.
+	maxEntries  int64
+	evictPolicy int64
+	// useClock orders uses of keys across all requests.
+	useClock uint64
+)
+
+// SetMaxEntries limits the number of entries stored per request to n.
+// Overwriting an existing key is always allowed; storing a new key beyond the
+// limit is handled according to the EvictionPolicy.
+//
+// If n <= 0, which is the default, the number of entries is unlimited.
+//
+// This protects long-lived requests from middleware that keeps storing
+// values under new keys. Values stored by the package for its own
+// bookkeeping, such as the user or the tenant, don't count toward the limit
+// and are never evicted.
+func SetMaxEntries(n int) {
+	atomic.StoreInt64(&maxEntries, int64(n))
+}
+
+// SetEvictionPolicy sets the policy applied when the limit configured with
+// SetMaxEntries is reached.
+func SetEvictionPolicy(p EvictionPolicy) {
+	atomic.StoreInt64(&evictPolicy, int64(p))
+}
+
+func loadInt(p *int64) int64 {
+	return atomic.LoadInt64(p)
+}
+
+// trackUse reports whether key uses must be recorded.
+func trackUse() bool {
+	return (loadInt(&maxEntries) > 0 || loadInt(&memLimit) > 0) &&
+		EvictionPolicy(loadInt(&evictPolicy)) == EvictLRU
+}
+
+// touch records a use of an existing key. Only the read lock is required:
+// the stamp is updated atomically and the maps are not modified.
+func (s *Store) touch(key interface{}) {
+	if stamp := s.used[key]; stamp != nil {
+		atomic.StoreUint64(stamp, atomic.AddUint64(&useClock, 1))
+	}
+}
+
+// touchNew records a use of a key, creating its stamp if needed. It must be
+// called with the store locked for writing.
+func (s *Store) touchNew(key interface{}) {
+	if s.used == nil {
+		s.used = make(map[interface{}]*uint64)
+	}
+	if s.used[key] == nil {
+		s.used[key] = new(uint64)
+	}
+	s.touch(key)
+}
+
+// evictLRU removes the least recently used entry other than keep, and
+// reports whether an entry was removed. Entries that were stored before
+// tracking was enabled count as the oldest. It must be called with the store
+// locked for writing.
+func (s *Store) evictLRU(keep interface{}) bool {
+	var (
+		oldest interface{}
+		min    uint64
+		found  bool
+	)
+	s.eachRaw(func(k, _ interface{}) bool {
+		if k == keep || isInternal(k) {
+			return true
+		}
+		var used uint64
+		if stamp := s.used[k]; stamp != nil {
+			used = atomic.LoadUint64(stamp)
+		}
+		if !found || used < min {
+			oldest, min, found = k, used, true
+		}
+		return true
+	})
+	if found {
+		// Only fails for frozen stores, which set rejects first.
+		_ = s.remove(oldest)
+	}
+	return found
+}
+
+// countApp returns the number of entries of the store, leaving out the
+// internal ones. It must be called with the store locked.
+func (s *Store) countApp() int {
+	n := 0
+	s.eachRaw(func(k, _ interface{}) bool {
+		if !isInternal(k) {
+			n++
+		}
+		return true
+	})
+	return n
+}
@@ -0,0 +1,79 @@
+package context
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestMaxEntriesLRU(t *testing.T) {
+	SetMaxEntries(2)
+	defer SetMaxEntries(0)
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+
+	Set(r, "a", 1)
+	Set(r, "b", 2)
+	// Use "a" so that "b" becomes the least recently used entry.
+	Get(r, "a")
+	if err := SetE(r, "c", 3); err != nil {
+		t.Fatalf("SetE returned %v", err)
+	}
+	if _, ok := GetOk(r, "b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	if Get(r, "a") != 1 || Get(r, "c") != 3 {
+		t.Errorf("Unexpected values %v", GetAll(r))
+	}
+
+	// Overwriting an existing key never evicts.
+	Set(r, "a", 10)
+	if storeLen(r) != 2 || Get(r, "c") != 3 {
+		t.Errorf("Unexpected values %v", GetAll(r))
+	}
+}
+
+func TestMaxEntriesReject(t *testing.T) {
+	SetMaxEntries(1)
+	SetEvictionPolicy(RejectNew)
+	defer func() {
+		SetMaxEntries(0)
+		SetEvictionPolicy(EvictLRU)
+	}()
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+
+	Set(r, "a", 1)
+	if err := SetE(r, "b", 2); err != ErrMaxEntries {
+		t.Errorf("Expected ErrMaxEntries, got %v", err)
+	}
+	if err := SetE(r, "a", 2); err != nil {
+		t.Errorf("Expected overwrite to succeed, got %v", err)
+	}
+	if _, ok := GetOk(r, "b"); ok {
+		t.Error("Expected b to be rejected")
+	}
+}
+
+func TestMaxEntriesInternal(t *testing.T) {
+	SetMaxEntries(2)
+	defer SetMaxEntries(0)
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+
+	SetUser(r, "admin")
+	Set(r, "a", 1)
+	Set(r, "b", 2)
+	if !IsAuthenticated(r) {
+		t.Error("Expected the user not to be evicted")
+	}
+	if Get(r, "a") != 1 || Get(r, "b") != 2 {
+		t.Errorf("Expected the internal entry not to count, got %v", GetAll(r))
+	}
+	Set(r, "c", 3)
+	if !IsAuthenticated(r) || Has(r, "a") {
+		t.Errorf("Expected an application entry to be evicted, got %v", Dump(r))
+	}
+}
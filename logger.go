@@ -0,0 +1,45 @@
+//go:build go1.21
+
+package context
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// SetLogger stores the logger of a request, as returned by Logger.
+func SetLogger(r *http.Request, l *slog.Logger) {
+	Set(r, loggerKey, l)
+}
+
+// Logger returns the logger stored for a request with SetLogger or
+// LoggerHandler, or slog.Default() if there is none.
+func Logger(r *http.Request) *slog.Logger {
+	if l, ok := Get(r, loggerKey).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// LoggerHandler wraps a handler so that every request gets a logger derived
+// from base, or slog.Default() if nil, with the method and path of the
+// request, and its "X-Request-Id" header if set:
+//
+//	http.Handle("/", context.ClearHandler(context.LoggerHandler(mux, nil)))
+//
+//	// In a handler:
+//	context.Logger(r).Info("done")
+func LoggerHandler(h http.Handler, base *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l := base
+		if l == nil {
+			l = slog.Default()
+		}
+		l = l.With("method", r.Method, "path", r.URL.Path)
+		if id := r.Header.Get("X-Request-Id"); id != "" {
+			l = l.With("request_id", id)
+		}
+		SetLogger(r, l)
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,36 @@
+//go:build go1.21
+
+package context
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/items", nil)
+	r.Header.Set("X-Request-Id", "42")
+	defer Clear(r)
+
+	if Logger(r) != slog.Default() {
+		t.Error("Expected the default logger")
+	}
+
+	var buf bytes.Buffer
+	base := slog.New(slog.NewTextHandler(&buf, nil))
+	LoggerHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Logger(r).Info("handled")
+	}), base).ServeHTTP(httptest.NewRecorder(), r)
+	if out := buf.String(); !strings.Contains(out, "method=GET path=/items request_id=42") {
+		t.Errorf("Unexpected output %q", out)
+	}
+
+	SetLogger(r, base)
+	if Logger(r) != base {
+		t.Error("Expected the stored logger")
+	}
+}
@@ -0,0 +1,100 @@
+package context
+
+import (
+	"expvar"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// metrics is read by every call, so it is accessed atomically instead of
+	// under the package lock.
+	metrics      int64
+	metricsOnce  sync.Once
+	metricsSets  expvar.Int
+	metricsGets  expvar.Int
+	metricsDels  expvar.Int
+	metricsClear expvar.Int
+	metricsPurge expvar.Int
+	metricsLeaks expvar.Int
+	// metricsTenantLeaks counts leaks by tenant.
+	metricsTenantLeaks expvar.Map
+)
+
+// EnableMetrics turns the publication of usage counters with expvar on or
+// off. It is off by default, so that calls don't pay for the counters.
+//
+// The counters are published under "gorilla/context" once metrics are first
+// enabled:
+//
+//	stores   requests currently holding a store
+//	sets     calls to Set and SetE
+//	gets     calls to Get and GetOk
+//	deletes  calls to Delete
+//	clears   requests cleared by Clear or ClearHandler
+//	purged   requests cleared by Purge
+//	leaked   requests cleared by Purge because they were older than maxAge,
+//	         meaning that nothing cleared them at the end of the request
+//	tenants  requests currently holding a store, by tenant
+//	leaked_by_tenant
+//	         leaked requests, by tenant
+//
+// Requests without a tenant are counted under "".
+//
+// Counters are not updated while metrics are off.
+func EnableMetrics(on bool) {
+	if on {
+		metricsOnce.Do(publishMetrics)
+		atomic.StoreInt64(&metrics, 1)
+	} else {
+		atomic.StoreInt64(&metrics, 0)
+	}
+}
+
+func publishMetrics() {
+	m := expvar.NewMap("gorilla/context")
+	m.Set("stores", expvar.Func(func() interface{} {
+		n := 0
+		currentBackend().Range(func(_ *http.Request, _ *Store) bool {
+			n++
+			return true
+		})
+		return n
+	}))
+	m.Set("sets", &metricsSets)
+	m.Set("gets", &metricsGets)
+	m.Set("deletes", &metricsDels)
+	m.Set("clears", &metricsClear)
+	m.Set("purged", &metricsPurge)
+	m.Set("leaked", &metricsLeaks)
+	m.Set("tenants", expvar.Func(func() interface{} {
+		tenants := make(map[string]int)
+		var rs []*http.Request
+		currentBackend().Range(func(r *http.Request, _ *Store) bool {
+			rs = append(rs, r)
+			return true
+		})
+		for _, r := range rs {
+			tenants[Tenant(r)]++
+		}
+		return tenants
+	}))
+	m.Set("leaked_by_tenant", &metricsTenantLeaks)
+}
+
+// countLeak counts a leaked request by tenant if metrics are enabled.
+func countLeak(vs *valueSet) {
+	if loadInt(&metrics) != 0 {
+		t, _ := vs.get(tenantKey)
+		name, _ := t.(string)
+		metricsTenantLeaks.Add(name, 1)
+	}
+}
+
+// countMetric adds n to a counter if metrics are enabled.
+func countMetric(c *expvar.Int, n int) {
+	if loadInt(&metrics) != 0 {
+		c.Add(int64(n))
+	}
+}
@@ -0,0 +1,76 @@
+package context
+
+import (
+	"expvar"
+	"net/http"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+
+	Set(r, key1, "1")
+	EnableMetrics(true)
+	defer EnableMetrics(false)
+	m := expvar.Get("gorilla/context").(*expvar.Map)
+	value := func(name string) string {
+		return m.Get(name).String()
+	}
+	sets, gets := metricsSets.Value(), metricsGets.Value()
+
+	Set(r, key2, "2")
+	Get(r, key1)
+	GetOk(r, key2)
+	if n := metricsSets.Value() - sets; n != 1 {
+		t.Errorf("Expected 1 set, got %d", n)
+	}
+	if n := metricsGets.Value() - gets; n != 2 {
+		t.Errorf("Expected 2 gets, got %d", n)
+	}
+	if v := value("stores"); v != "1" {
+		t.Errorf("Expected 1 live store, got %s", v)
+	}
+
+	clears, purged := metricsClear.Value(), metricsPurge.Value()
+	Clear(r)
+	Set(r, key1, "1")
+	Purge(0)
+	if n := metricsClear.Value() - clears; n != 1 {
+		t.Errorf("Expected 1 clear, got %d", n)
+	}
+	if n := metricsPurge.Value() - purged; n != 1 {
+		t.Errorf("Expected 1 purged store, got %d", n)
+	}
+	if v := value("stores"); v != "0" {
+		t.Errorf("Expected no live stores, got %s", v)
+	}
+
+	EnableMetrics(false)
+	sets = metricsSets.Value()
+	Set(r, key1, "1")
+	defer Clear(r)
+	if n := metricsSets.Value() - sets; n != 0 {
+		t.Errorf("Expected no counting once disabled, got %d sets", n)
+	}
+}
+
+func TestMetricsTyped(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	k := NewKey[int]("metrics")
+
+	EnableMetrics(true)
+	defer EnableMetrics(false)
+	sets, gets := metricsSets.Value(), metricsGets.Value()
+
+	SetS(r, "key", "value")
+	GetS(r, "key")
+	k.Set(r, 1)
+	k.Get(r)
+	if n := metricsSets.Value() - sets; n != 2 {
+		t.Errorf("Expected 2 sets, got %d", n)
+	}
+	if n := metricsGets.Value() - gets; n != 2 {
+		t.Errorf("Expected 2 gets, got %d", n)
+	}
+}
@@ -0,0 +1,116 @@
+package context
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DeletePrefix removes all values stored for a given request under string
+// keys that start with prefix. Keys of other types are left untouched.
+func DeletePrefix(r *http.Request, prefix string) {
+	deleteMatching(r, func(k interface{}) bool {
+		str, ok := k.(string)
+		return ok && strings.HasPrefix(str, prefix)
+	})
+}
+
+// ClearNamespace removes all values stored for a given request under string
+// keys of the form "ns.name", leaving values owned by other packages in place.
+// Values stored through the Namespace view of ns are left to its Clear.
+//
+// For example, a middleware storing "auth.user" and "auth.token" can remove
+// both with:
+//
+//	context.ClearNamespace(r, "auth")
+func ClearNamespace(r *http.Request, ns string) {
+	DeletePrefix(r, ns+".")
+}
+
+// deleteMatching removes the values whose key matches. They are removed with
+// DeleteE, so that hooks, recorders and History see every deletion.
+func deleteMatching(r *http.Request, match func(k interface{}) bool) {
+	var keys []interface{}
+	vs, s := view(r)
+	if vs == nil {
+		return
+	}
+	vs.eachRaw(func(k, _ interface{}) bool {
+		if match(k) {
+			keys = append(keys, k)
+		}
+		return true
+	})
+	if s != nil {
+		s.mu.RUnlock()
+	}
+	for _, k := range keys {
+		if DeleteE(r, k) != nil {
+			// Frozen: nothing can be removed.
+			break
+		}
+	}
+}
+
+// nsKey is the key of the values stored through a Namespace.
+type nsKey struct {
+	ns  string
+	key interface{}
+}
+
+func (k nsKey) String() string {
+	return k.ns + "." + fmt.Sprint(k.key)
+}
+
+// NamespaceStore is a view of the values of a request that only sees the keys of
+// one namespace. Libraries store their values through a namespace named
+// after their import path, so that they can't clobber the values of the
+// application or of other libraries, whatever keys they use.
+type NamespaceStore struct {
+	r  *http.Request
+	ns string
+}
+
+// Namespace returns the view of the namespace ns of a request:
+//
+//	auth := context.Namespace(r, "github.com/me/auth")
+//	auth.Set("user", user)
+func Namespace(r *http.Request, ns string) NamespaceStore {
+	return NamespaceStore{r: r, ns: ns}
+}
+
+// Set stores a value for a given key in the namespace, like Set.
+func (n NamespaceStore) Set(key, val interface{}) {
+	Set(n.r, nsKey{n.ns, key}, val)
+}
+
+// SetE stores a value for a given key in the namespace, like SetE.
+func (n NamespaceStore) SetE(key, val interface{}) error {
+	return SetE(n.r, nsKey{n.ns, key}, val)
+}
+
+// Get returns the value stored for a given key in the namespace.
+func (n NamespaceStore) Get(key interface{}) interface{} {
+	return Get(n.r, nsKey{n.ns, key})
+}
+
+// GetOk returns the value stored for a given key in the namespace and
+// whether it was present.
+func (n NamespaceStore) GetOk(key interface{}) (interface{}, bool) {
+	return GetOk(n.r, nsKey{n.ns, key})
+}
+
+// Delete removes the value stored for a given key in the namespace.
+func (n NamespaceStore) Delete(key interface{}) {
+	Delete(n.r, nsKey{n.ns, key})
+}
+
+// Clear removes all values of the namespace. Values stored under string keys
+// are left in place, even if their name starts with the namespace: they
+// belong to the application.
+func (n NamespaceStore) Clear() {
+	deleteMatching(n.r, func(k interface{}) bool {
+		nk, ok := k.(nsKey)
+		return ok && nk.ns == n.ns
+	})
+}
@@ -0,0 +1,84 @@
+package context
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDeletePrefix(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+
+	Set(r, "auth.user", "gorilla")
+	Set(r, "auth.token", "secret")
+	Set(r, "authority", "x")
+	Set(r, "session.id", "1")
+	Set(r, key1, "1")
+
+	ClearNamespace(r, "auth")
+	if storeLen(r) != 3 {
+		t.Errorf("Expected 3 values, got %v", GetAll(r))
+	}
+	if _, ok := GetOk(r, "authority"); !ok {
+		t.Error("Expected authority to be kept")
+	}
+
+	DeletePrefix(r, "sess")
+	if _, ok := GetOk(r, "session.id"); ok {
+		t.Error("Expected session.id to be deleted")
+	}
+	if Get(r, key1) != "1" {
+		t.Error("Expected non-string keys to be kept")
+	}
+}
+
+func TestNamespaceStore(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	auth := Namespace(r, "github.com/me/auth")
+	other := Namespace(r, "github.com/you/auth")
+	Set(r, "user", "app")
+	auth.Set("user", "gopher")
+	other.Set("user", "alice")
+	Set(r, "github.com/me/auth.token", "secret")
+
+	if Get(r, "user") != "app" || auth.Get("user") != "gopher" || other.Get("user") != "alice" {
+		t.Errorf("Expected namespaces to be isolated, got %v", GetAll(r))
+	}
+	if d := Dump(r); !strings.Contains(d, "github.com/me/auth.user (context.nsKey) = gopher") {
+		t.Errorf("Expected Dump to name the namespace, got %q", d)
+	}
+	auth.Clear()
+	if _, ok := auth.GetOk("user"); ok {
+		t.Error("Expected the namespace to be cleared")
+	}
+	if Get(r, "github.com/me/auth.token") != "secret" {
+		t.Error("Expected Clear to keep the string keys of the application")
+	}
+	if Get(r, "user") != "app" || other.Get("user") != "alice" {
+		t.Errorf("Expected other values to be kept, got %v", GetAll(r))
+	}
+}
+
+func TestDeletePrefixHooks(t *testing.T) {
+	var deleted []interface{}
+	SetTraceHooks(Hooks{OnDelete: func(r *http.Request, key interface{}, caller string) {
+		deleted = append(deleted, key)
+	}})
+	defer SetTraceHooks(Hooks{})
+	EnableHistory(true)
+	defer EnableHistory(false)
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	Set(r, "auth.user", "gorilla")
+	Set(r, "authority", "x")
+	ClearNamespace(r, "auth")
+	if len(deleted) != 1 || deleted[0] != "auth.user" {
+		t.Errorf("Expected the deletion to be traced, got %v", deleted)
+	}
+	if h := History(r, "auth.user"); len(h) != 2 || !h[1].Deleted {
+		t.Errorf("Expected the deletion to be recorded, got %+v", h)
+	}
+}
@@ -0,0 +1,28 @@
+package context
+
+import (
+	"net/http"
+)
+
+// onceValue boxes the values set with SetOnce, so that Dump can tell them
+// apart.
+type onceValue struct {
+	v interface{}
+}
+
+func (o onceValue) unbox() interface{} {
+	return o.v
+}
+
+// SetOnce stores a value meant to be consumed once with PopOnce, such as a
+// hint for a handler further down the chain. Until it is popped, the value
+// can be read like any other, and Dump marks it.
+func SetOnce(r *http.Request, key, val interface{}) {
+	Set(r, key, onceValue{val})
+}
+
+// PopOnce returns the value stored for a given key in a given request, and
+// removes it, like Pop.
+func PopOnce(r *http.Request, key interface{}) (interface{}, bool) {
+	return Pop(r, key)
+}
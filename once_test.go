@@ -0,0 +1,29 @@
+package context
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSetOnce(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	SetOnce(r, "redirect", "/login")
+	Set(r, key1, "1")
+	if v := Get(r, "redirect"); v != "/login" {
+		t.Errorf("Expected the value to be readable, got %v", v)
+	}
+	if d := Dump(r); !strings.Contains(d, "redirect (string) = /login (string) [once]") || strings.Count(d, "[once]") != 1 {
+		t.Errorf("Expected Dump to mark the value, got %q", d)
+	}
+	if v, ok := PopOnce(r, "redirect"); !ok || v != "/login" {
+		t.Errorf("Expected /login, got %v", v)
+	}
+	if _, ok := PopOnce(r, "redirect"); ok {
+		t.Error("Expected the value to be consumed")
+	}
+	if v, ok := PopOnce(r, key1); !ok || v != "1" {
+		t.Errorf("Expected PopOnce to work for other values, got %v", v)
+	}
+}
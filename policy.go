@@ -0,0 +1,79 @@
+package context
+
+import (
+	"errors"
+	"log"
+	"sync/atomic"
+)
+
+// ErrUnknownKey is returned by SetE for keys refused by the RejectUnknownKeys
+// policy.
+var ErrUnknownKey = errors.New("context: key not allowed by the key policy")
+
+// KeyPolicy decides what happens when a value is stored under a key that
+// was not registered with AllowKey.
+type KeyPolicy int
+
+const (
+	// AllowAllKeys stores values under any key. This is the default.
+	AllowAllKeys KeyPolicy = iota
+	// LogUnknownKeys stores the value and logs the key, with the location
+	// of the call.
+	LogUnknownKeys
+	// RejectUnknownKeys refuses the value, like the limits of SetMaxEntries.
+	RejectUnknownKeys
+)
+
+var (
+	// keyPolicy is read by every Set, so it is accessed atomically.
+	keyPolicy int64
+	// allowedKeys holds the keys registered with AllowKey, as a
+	// map[interface{}]struct{} replaced on every change, so that Set doesn't
+	// take the package lock with a store locked.
+	allowedKeys atomic.Value
+)
+
+// AllowKey registers keys for the policy set with SetKeyPolicy. The
+// subpackages of gorilla/context register their own keys, and keys used by
+// the package itself are always allowed. It is meant to be called during
+// program initialization.
+func AllowKey(keys ...interface{}) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	old, _ := allowedKeys.Load().(map[interface{}]struct{})
+	m := make(map[interface{}]struct{}, len(old)+len(keys))
+	for k := range old {
+		m[k] = struct{}{}
+	}
+	for _, k := range keys {
+		m[k] = struct{}{}
+	}
+	allowedKeys.Store(m)
+}
+
+// SetKeyPolicy restricts the keys values can be stored under to the keys
+// registered with AllowKey, so that teams control what is stored for
+// requests and typos in string keys are caught.
+func SetKeyPolicy(p KeyPolicy) {
+	atomic.StoreInt64(&keyPolicy, int64(p))
+}
+
+// checkKey applies the key policy to a new value.
+func checkKey(key interface{}) error {
+	p := KeyPolicy(loadInt(&keyPolicy))
+	if p == AllowAllKeys {
+		return nil
+	}
+	if _, ok := key.(internalKey); ok {
+		return nil
+	}
+	m, _ := allowedKeys.Load().(map[interface{}]struct{})
+	if _, ok := m[key]; ok {
+		return nil
+	}
+	if p == RejectUnknownKeys {
+		return ErrUnknownKey
+	}
+	log.Printf("context: unknown key %v (%T) stored at %s", key, key, caller())
+	return nil
+}
@@ -0,0 +1,42 @@
+package context
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestKeyPolicy(t *testing.T) {
+	AllowKey("user", key1)
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+
+	SetKeyPolicy(RejectUnknownKeys)
+	defer SetKeyPolicy(AllowAllKeys)
+	if err := SetE(r, "user", "gopher"); err != nil {
+		t.Errorf("Expected an allowed key to be stored, got %v", err)
+	}
+	if err := SetE(r, "usr", "gopher"); err != ErrUnknownKey {
+		t.Errorf("Expected ErrUnknownKey, got %v", err)
+	}
+	SetS(r, "usr", "gopher")
+	SetTenant(r, "acme")
+	if _, ok := GetOk(r, "usr"); ok || Tenant(r) != "acme" {
+		t.Errorf("Unexpected values %v", GetAll(r))
+	}
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(log.Writer())
+	SetKeyPolicy(LogUnknownKeys)
+	Set(r, key2, "2")
+	if Get(r, key2) != "2" {
+		t.Error("Expected the unknown key to be stored")
+	}
+	if s := buf.String(); !strings.Contains(s, "unknown key 1 (context.keyType) stored at") ||
+		!strings.Contains(s, "policy_test.go") {
+		t.Errorf("Unexpected log %q", s)
+	}
+}
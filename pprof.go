@@ -0,0 +1,39 @@
+package context
+
+import (
+	stdcontext "context"
+	"fmt"
+	"net/http"
+	"runtime/pprof"
+)
+
+// Label names the profiler label a request value is applied as.
+type Label struct {
+	Key  interface{}
+	Name string
+}
+
+// LabelHandler wraps a handler so that the values stored for labels when a
+// request reaches it are applied as pprof labels while h runs, formatted
+// with fmt.Sprint. CPU profiles can then be broken down by route, tenant or
+// any other request value. Values that are not stored are skipped.
+//
+// Goroutines started by h inherit the labels. The request is passed to h
+// unchanged: the labels are not added to its context.
+func LabelHandler(h http.Handler, labels ...Label) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		kvs := make([]string, 0, 2*len(labels))
+		for _, l := range labels {
+			if v, ok := GetOk(r, l.Key); ok {
+				kvs = append(kvs, l.Name, fmt.Sprint(v))
+			}
+		}
+		if len(kvs) == 0 {
+			h.ServeHTTP(w, r)
+			return
+		}
+		pprof.Do(r.Context(), pprof.Labels(kvs...), func(stdcontext.Context) {
+			h.ServeHTTP(w, r)
+		})
+	})
+}
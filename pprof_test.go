@@ -0,0 +1,30 @@
+package context
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"runtime/pprof"
+	"strings"
+	"testing"
+)
+
+func TestLabelHandler(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	Set(r, key1, "/items")
+
+	var profile bytes.Buffer
+	h := LabelHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pprof.Lookup("goroutine").WriteTo(&profile, 1)
+	}), Label{key1, "route"}, Label{key2, "tenant"})
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	out := profile.String()
+	if !strings.Contains(out, `"route":"/items"`) {
+		t.Errorf("Expected the route label in the profile:\n%s", out)
+	}
+	if strings.Contains(out, `"tenant"`) {
+		t.Error("Expected missing values to be skipped")
+	}
+}
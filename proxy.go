@@ -0,0 +1,66 @@
+package context
+
+import (
+	stdcontext "context"
+	"fmt"
+	"net/http"
+)
+
+// originKey is the request context key ProxyHandler stores the incoming
+// request under.
+type originKey struct{}
+
+// ProxyHeader names the header a value is sent in by ProxyDirector.
+type ProxyHeader struct {
+	Key    interface{}
+	Header string
+}
+
+// ProxyHandler wraps an httputil.ReverseProxy, or any handler building
+// outgoing requests from incoming ones, so that the incoming request can be
+// found from the outgoing ones with Incoming:
+//
+//	proxy := httputil.NewSingleHostReverseProxy(target)
+//	proxy.Director = context.ProxyDirector(proxy.Director,
+//		context.ProxyHeader{Key: userKey, Header: "X-User"})
+//	http.Handle("/", context.ClearHandler(context.ProxyHandler(proxy)))
+func ProxyHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := stdcontext.WithValue(r.Context(), originKey{}, r)
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Incoming returns the request handled by ProxyHandler that an outgoing
+// request was built from, or nil. Transports and response modifiers of the
+// proxy use it to read the values of the incoming request.
+//
+// The values are not copied to the outgoing request itself: ReverseProxy
+// hands copies of it to its transport, which would not see them, and nothing
+// would clear them.
+func Incoming(out *http.Request) *http.Request {
+	in, _ := out.Context().Value(originKey{}).(*http.Request)
+	return in
+}
+
+// ProxyDirector returns an httputil.ReverseProxy director that calls base,
+// if not nil, then sets the given headers of the outgoing request to the
+// values stored for the incoming one, formatted with fmt.Sprint. Values
+// that are not stored leave their header alone. The proxy must be wrapped
+// with ProxyHandler.
+func ProxyDirector(base func(*http.Request), headers ...ProxyHeader) func(*http.Request) {
+	return func(out *http.Request) {
+		if base != nil {
+			base(out)
+		}
+		in := Incoming(out)
+		if in == nil {
+			return
+		}
+		for _, h := range headers {
+			if v, ok := GetOk(in, h.Key); ok {
+				out.Header.Set(h.Header, fmt.Sprint(v))
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package context
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+)
+
+func TestProxyDirector(t *testing.T) {
+	var header string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("X-Key")
+	}))
+	defer backend.Close()
+	target, _ := url.Parse(backend.URL)
+
+	var seen interface{}
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Director = ProxyDirector(proxy.Director, ProxyHeader{key1, "X-Key"}, ProxyHeader{key2, "X-Missing"})
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		seen = Get(Incoming(resp.Request), key1)
+		return nil
+	}
+	h := ClearHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Set(r, key1, 42)
+		ProxyHandler(proxy).ServeHTTP(w, r)
+	}))
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if header != "42" {
+		t.Errorf("Expected the value in a header, got %q", header)
+	}
+	if seen != 42 {
+		t.Errorf("Expected the incoming request to be found, got %v", seen)
+	}
+	if n := storeCount(); n != 0 {
+		t.Errorf("Expected no stores left, got %d", n)
+	}
+	if Incoming(r) != nil {
+		t.Error("Expected no incoming request outside ProxyHandler")
+	}
+}
@@ -0,0 +1,42 @@
+package context
+
+import (
+	"net/http"
+)
+
+// Find returns the values stored for a given request for which match returns
+// true, keyed by their key. It is nil if none matched. match is called like
+// the function passed to Range, and must not change the values either.
+func Find(r *http.Request, match func(key, val interface{}) bool) map[interface{}]interface{} {
+	var found map[interface{}]interface{}
+	Range(r, func(k, v interface{}) bool {
+		if match(k, v) {
+			if found == nil {
+				found = make(map[interface{}]interface{})
+			}
+			found[k] = v
+		}
+		return true
+	})
+	return found
+}
+
+// OfType returns the values of type T stored for a given request, whatever
+// their key, in no particular order. Middleware can collect every error or
+// audit event the handlers stored without knowing their keys:
+//
+//	for _, e := range context.OfType[AuditEvent](r) {
+//		audit.Log(e)
+//	}
+//
+// If T is an interface, the values implementing it are returned.
+func OfType[T any](r *http.Request) []T {
+	var found []T
+	Range(r, func(_, v interface{}) bool {
+		if t, ok := v.(T); ok {
+			found = append(found, t)
+		}
+		return true
+	})
+	return found
+}
@@ -0,0 +1,47 @@
+package context
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	if Find(r, func(k, v interface{}) bool { return true }) != nil {
+		t.Error("Expected no values for an unregistered request")
+	}
+	Set(r, "cache.max-age", 60)
+	Set(r, "cache.private", true)
+	Set(r, "user", "gopher")
+
+	found := Find(r, func(k, v interface{}) bool {
+		s, ok := k.(string)
+		return ok && strings.HasPrefix(s, "cache.")
+	})
+	if len(found) != 2 || found["cache.max-age"] != 60 || found["cache.private"] != true {
+		t.Errorf("Unexpected values %v", found)
+	}
+}
+
+func TestOfType(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	errA, errB := errors.New("a"), errors.New("b")
+	Set(r, key1, errA)
+	Set(r, key2, errB)
+	Set(r, "user", "gopher")
+	Set(r, "count", 1)
+
+	if errs := OfType[error](r); len(errs) != 2 || (errs[0] != errA && errs[1] != errA) {
+		t.Errorf("Unexpected errors %v", errs)
+	}
+	if s := OfType[string](r); len(s) != 1 || s[0] != "gopher" {
+		t.Errorf("Unexpected strings %v", s)
+	}
+	if f := OfType[float64](r); f != nil {
+		t.Errorf("Expected no float64 values, got %v", f)
+	}
+}
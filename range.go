@@ -0,0 +1,90 @@
+package context
+
+import (
+	"net/http"
+)
+
+// Range calls f for every value stored for a given request, in no particular
+// order, until f returns false. The values are those of GetAll, read under
+// the lock of the store instead of copied to a map: f must not change the
+// values of the request, nor keep the store busy for long.
+func Range(r *http.Request, f func(key, val interface{}) bool) {
+	rangeValues(r, f, nil)
+}
+
+// rangeValues is Range, skipping the keys in seen, which holds the keys
+// already iterated over in child requests. It returns false if f stopped the
+// iteration.
+func rangeValues(r *http.Request, f func(key, val interface{}) bool, seen map[interface{}]struct{}) bool {
+	// Look the parent up first: the store of r is locked below.
+	p, hidden := inherited(r)
+	if p != nil && seen == nil {
+		seen = make(map[interface{}]struct{})
+	}
+	for k := range hidden {
+		seen[k] = struct{}{}
+	}
+	vs, s := view(r)
+	if vs == nil {
+		return true
+	}
+	more := true
+	vs.each(func(k, v interface{}) bool {
+		if _, ok := k.(internalKey); ok {
+			return true
+		}
+		if seen != nil {
+			if _, ok := seen[k]; ok {
+				return true
+			}
+			seen[k] = struct{}{}
+		}
+		more = f(k, v)
+		return more
+	})
+	if s != nil {
+		s.mu.RUnlock()
+	}
+	if more && p != nil {
+		return rangeValues(p, f, seen)
+	}
+	return more
+}
+
+// Keys returns the keys of the values stored for a given request, in no
+// particular order. It is nil if there are none.
+func Keys(r *http.Request) []interface{} {
+	var keys []interface{}
+	Range(r, func(k, _ interface{}) bool {
+		keys = append(keys, k)
+		return true
+	})
+	return keys
+}
+
+// Len returns the number of values stored for a given request, that is of
+// values GetAll would return, without copying them.
+func Len(r *http.Request) int {
+	if parentOf(r) != nil {
+		n := 0
+		Range(r, func(_, _ interface{}) bool {
+			n++
+			return true
+		})
+		return n
+	}
+	vs, s := view(r)
+	if vs == nil {
+		return 0
+	}
+	n := vs.len() - vs.pending()
+	for i := internalKey(0); int(i) < len(internalKeyNames); i++ {
+		if _, ok := vs.getRaw(i); ok {
+			n--
+		}
+	}
+	if s != nil {
+		s.mu.RUnlock()
+	}
+	return n
+}
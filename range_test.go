@@ -0,0 +1,84 @@
+package context
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRange(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	Range(r, func(k, v interface{}) bool {
+		t.Errorf("Unexpected value %v for unregistered request", k)
+		return true
+	})
+
+	Set(r, key1, "1")
+	SetS(r, "user", "gopher")
+	SetTenant(r, "acme")
+	got := make(map[interface{}]interface{})
+	Range(r, func(k, v interface{}) bool {
+		got[k] = v
+		return true
+	})
+	if len(got) != 2 || got[key1] != "1" || got["user"] != "gopher" {
+		t.Errorf("Unexpected values %v", got)
+	}
+
+	n := 0
+	Range(r, func(k, v interface{}) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Errorf("Expected Range to stop after 1 value, got %d", n)
+	}
+
+	c := Child(r)
+	defer Clear(c)
+	Set(c, key1, "child")
+	Set(c, key2, "2")
+	got = make(map[interface{}]interface{})
+	Range(c, func(k, v interface{}) bool {
+		if _, dup := got[k]; dup {
+			t.Errorf("Duplicate key %v", k)
+		}
+		got[k] = v
+		return true
+	})
+	if len(got) != 3 || got[key1] != "child" || got["user"] != "gopher" {
+		t.Errorf("Unexpected child values %v", got)
+	}
+}
+
+func TestKeysLen(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	if Len(r) != 0 || Keys(r) != nil {
+		t.Error("Expected no keys for an unregistered request")
+	}
+	Set(r, key1, "1")
+	SetS(r, "user", "gopher")
+	SetTenant(r, "acme")
+	if n := Len(r); n != 2 {
+		t.Errorf("Expected 2 values, got %d", n)
+	}
+	keys := Keys(r)
+	if len(keys) != 2 || (keys[0] != key1 && keys[1] != key1) {
+		t.Errorf("Unexpected keys %v", keys)
+	}
+	if n := testing.AllocsPerRun(10, func() { Len(r) }); n != 0 {
+		t.Errorf("Expected Len not to allocate, got %v", n)
+	}
+
+	SetLazy(r, key2, func() (interface{}, error) {
+		return "2", nil
+	})
+	if n := Len(r); n != 2 || len(Keys(r)) != 2 {
+		t.Errorf("Expected lazy values to be left out until computed, got %d", n)
+	}
+	Get(r, key2)
+	if n := Len(r); n != 3 {
+		t.Errorf("Expected 3 values once computed, got %d", n)
+	}
+}
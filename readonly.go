@@ -0,0 +1,43 @@
+package context
+
+import (
+	"net/http"
+)
+
+// ReadOnlyStore gives read access to the values of a request, for code such
+// as plugins or template helpers that must not change them.
+type ReadOnlyStore interface {
+	// Get returns the value stored for key.
+	Get(key interface{}) interface{}
+	// GetOk returns the value stored for key and whether it was present.
+	GetOk(key interface{}) (interface{}, bool)
+	// Range calls f for every value, in no particular order, until f returns
+	// false. It iterates over a copy: f may use the package.
+	Range(f func(key, val interface{}) bool)
+}
+
+// ReadOnly returns a read-only view of the values of a request. The view
+// reads the current values: changes made through the package are visible.
+func ReadOnly(r *http.Request) ReadOnlyStore {
+	return readOnly{r}
+}
+
+type readOnly struct {
+	r *http.Request
+}
+
+func (ro readOnly) Get(key interface{}) interface{} {
+	return Get(ro.r, key)
+}
+
+func (ro readOnly) GetOk(key interface{}) (interface{}, bool) {
+	return GetOk(ro.r, key)
+}
+
+func (ro readOnly) Range(f func(key, val interface{}) bool) {
+	for k, v := range GetAll(ro.r) {
+		if !f(k, v) {
+			return
+		}
+	}
+}
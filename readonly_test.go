@@ -0,0 +1,39 @@
+package context
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestReadOnly(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	ro := ReadOnly(r)
+	if _, ok := ro.GetOk(key1); ok {
+		t.Error("Expected no value")
+	}
+	Set(r, key1, "1")
+	Set(r, key2, "2")
+	if ro.Get(key1) != "1" {
+		t.Errorf("Expected 1, got %v", ro.Get(key1))
+	}
+	seen := map[interface{}]interface{}{}
+	ro.Range(func(k, v interface{}) bool {
+		seen[k] = v
+		return true
+	})
+	if len(seen) != 2 || seen[key2] != "2" {
+		t.Errorf("Unexpected values %v", seen)
+	}
+	n := 0
+	ro.Range(func(k, v interface{}) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Errorf("Expected Range to stop, got %d calls", n)
+	}
+	if _, ok := ro.(MutableStore); ok {
+		t.Error("Expected the view not to be mutable")
+	}
+}
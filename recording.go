@@ -0,0 +1,102 @@
+package context
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// OpKind is the kind of an operation recorded by a RecordingBackend.
+type OpKind int
+
+const (
+	// OpSet is recorded by Set, SetE, SetS, SetOnce and Key.Set.
+	OpSet OpKind = iota
+	// OpGet is recorded by Get, GetOk, GetS and Key.Get.
+	OpGet
+	// OpDelete is recorded by Delete, PopOnce and Key.Delete.
+	OpDelete
+	// OpClear is recorded by Clear, ClearHandler and Purge.
+	OpClear
+)
+
+var opNames = [...]string{
+	OpSet:    "Set",
+	OpGet:    "Get",
+	OpDelete: "Delete",
+	OpClear:  "Clear",
+}
+
+func (k OpKind) String() string {
+	return opNames[k]
+}
+
+// Op is an operation recorded by a RecordingBackend.
+type Op struct {
+	Kind    OpKind
+	Request *http.Request
+	// Key is the key of the operation, nil for OpClear.
+	Key interface{}
+	// Value is the value stored by OpSet, nil otherwise.
+	Value interface{}
+}
+
+// RecordingBackend is a Backend recording the operations on request values,
+// in order, so that tests can verify what middleware does. It stores values
+// with the wrapped backend.
+//
+// Operations are only recorded while the backend is installed with
+// SetBackend.
+type RecordingBackend struct {
+	Backend
+	mu  sync.Mutex
+	ops []Op
+}
+
+// NewRecordingBackend returns a RecordingBackend storing values with b, or
+// with a new map backend if b is nil.
+func NewRecordingBackend(b Backend) *RecordingBackend {
+	if b == nil {
+		b = NewMapBackend()
+	}
+	return &RecordingBackend{Backend: b}
+}
+
+// Ops returns the operations recorded so far.
+func (b *RecordingBackend) Ops() []Op {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Op(nil), b.ops...)
+}
+
+// Reset forgets the operations recorded so far.
+func (b *RecordingBackend) Reset() {
+	b.mu.Lock()
+	b.ops = nil
+	b.mu.Unlock()
+}
+
+func (b *RecordingBackend) add(op Op) {
+	b.mu.Lock()
+	b.ops = append(b.ops, op)
+	b.mu.Unlock()
+}
+
+// recorder is the installed RecordingBackend, if any. It is read on every
+// call, so it is accessed atomically.
+var recorder atomic.Pointer[RecordingBackend]
+
+// observe records an operation if a RecordingBackend is installed.
+func observe(kind OpKind, r *http.Request, key, val interface{}) {
+	if b := recorder.Load(); b != nil {
+		b.add(Op{Kind: kind, Request: r, Key: key, Value: val})
+	}
+}
+
+// observeS is observe for string keys, only converting the key to
+// interface{} if needed.
+func observeS(kind OpKind, r *http.Request, key string, val interface{}) {
+	if b := recorder.Load(); b != nil {
+		b.add(Op{Kind: kind, Request: r, Key: key, Value: val})
+	}
+}
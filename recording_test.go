@@ -0,0 +1,46 @@
+package context
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestRecordingBackend(t *testing.T) {
+	b := NewRecordingBackend(nil)
+	SetBackend(b)
+	defer SetBackend(NewMapBackend())
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	k := NewKey[int]("count")
+	Set(r, key1, "1")
+	SetS(r, "user", "gopher")
+	k.Set(r, 2)
+	Get(r, key1)
+	GetS(r, "user")
+	k.Get(r)
+	Delete(r, key1)
+	Clear(r)
+
+	want := []Op{
+		{Kind: OpSet, Request: r, Key: key1, Value: "1"},
+		{Kind: OpSet, Request: r, Key: "user", Value: "gopher"},
+		{Kind: OpSet, Request: r, Key: k, Value: 2},
+		{Kind: OpGet, Request: r, Key: key1},
+		{Kind: OpGet, Request: r, Key: "user"},
+		{Kind: OpGet, Request: r, Key: k},
+		{Kind: OpDelete, Request: r, Key: key1},
+		{Kind: OpClear, Request: r},
+	}
+	if got := b.Ops(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected operations:\n%v\nwant:\n%v", got, want)
+	}
+
+	b.Reset()
+	SetBackend(NewMapBackend())
+	Set(r, key1, "1")
+	Clear(r)
+	if ops := b.Ops(); len(ops) != 0 {
+		t.Errorf("Expected no operations once uninstalled, got %v", ops)
+	}
+}
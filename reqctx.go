@@ -0,0 +1,90 @@
+package context
+
+import (
+	stdcontext "context"
+	"net/http"
+	"sync/atomic"
+)
+
+// requestKey is the context key LogContext stores requests under.
+type requestKey struct{}
+
+// requestHolder lets a request context refer to the request itself, and to
+// the request whose values it shares.
+type requestHolder struct {
+	r    *http.Request
+	orig *http.Request
+}
+
+// aliased is set once Alias was called, so that lookups of requests without
+// a store only search their context for the request they share values with
+// when there may be one.
+var aliased int64
+
+// Alias returns a shallow copy of r with a context that refers to r, sharing
+// the values of r: values set, deleted or frozen through either request are
+// those of the other, and clearing either clears both. Requests derived from
+// the copy with WithContext, such as those routers and tracing middleware
+// pass on, share them too, unless they are given values of their own first.
+//
+// The copy can be found from its context, and from contexts derived from
+// it, with RequestFromContext.
+func Alias(r *http.Request) *http.Request {
+	orig := r
+	if o := aliasOf(r); o != nil {
+		orig = o
+	}
+	holder := &requestHolder{orig: orig}
+	in := r.WithContext(stdcontext.WithValue(r.Context(), requestKey{}, holder))
+	holder.r = in
+	atomic.StoreInt64(&aliased, 1)
+	return in
+}
+
+// aliasOf returns the request r shares values with, or nil.
+func aliasOf(r *http.Request) *http.Request {
+	if loadInt(&aliased) == 0 {
+		return nil
+	}
+	holder, _ := r.Context().Value(requestKey{}).(*requestHolder)
+	if holder == nil || holder.orig == r {
+		return nil
+	}
+	return holder.orig
+}
+
+// unaliased returns a shallow copy of r that doesn't share the values of r
+// even if r was made with Alias, for requests such as a Child that have
+// values of their own. Requests derived from the copy share those.
+func unaliased(r *http.Request) *http.Request {
+	if loadInt(&aliased) == 0 {
+		return r.WithContext(r.Context())
+	}
+	holder := &requestHolder{}
+	c := r.WithContext(stdcontext.WithValue(r.Context(), requestKey{}, holder))
+	holder.r, holder.orig = c, c
+	return c
+}
+
+// LogContext wraps a handler so that the request can be found from its
+// context with RequestFromContext, as done by NewLogHandler.
+//
+// Since a request context can't be changed in place, h is called with a
+// copy of the request made by Alias, which shares the values of the
+// request.
+func LogContext(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, Alias(r))
+	})
+}
+
+// RequestFromContext returns the request whose context, or a context derived
+// from it, is ctx, if it went through LogContext or Alias. Otherwise it
+// returns nil. This lets code that only gets a context, such as log
+// handlers, read request values.
+func RequestFromContext(ctx stdcontext.Context) *http.Request {
+	if holder, ok := ctx.Value(requestKey{}).(*requestHolder); ok {
+		return holder.r
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package context
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLogContext(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	Set(r, key1, "1")
+
+	if RequestFromContext(r.Context()) != nil {
+		t.Error("Expected no request outside LogContext")
+	}
+	LogContext(http.HandlerFunc(func(w http.ResponseWriter, in *http.Request) {
+		found := RequestFromContext(in.Context())
+		if found != in || Get(found, key1) != "1" {
+			t.Errorf("Expected the request with its values, got %v", found)
+		}
+		Set(in, key2, "2")
+	})).ServeHTTP(httptest.NewRecorder(), r)
+	if Get(r, key2) != "2" {
+		t.Error("Expected values to be moved back")
+	}
+}
+
+func TestLogContextSharesStore(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	Set(r, key1, "1")
+	SetOnce(r, "redirect", "/login")
+	Freeze(r)
+	deferred := false
+
+	LogContext(http.HandlerFunc(func(w http.ResponseWriter, in *http.Request) {
+		if !IsFrozen(in) {
+			t.Error("Expected the copy to be frozen")
+		}
+		if err := SetE(in, "user", "mallory"); err != ErrFrozen {
+			t.Errorf("Expected ErrFrozen, got %v", err)
+		}
+		if d := Dump(in); !strings.Contains(d, "[once]") {
+			t.Errorf("Expected the once value to be kept, got %q", d)
+		}
+		Defer(in, func() { deferred = true })
+		// Derived requests, like those of routers, share the values too.
+		derived := in.WithContext(in.Context())
+		if Get(derived, key1) != "1" {
+			t.Error("Expected derived requests to share the values")
+		}
+	})).ServeHTTP(httptest.NewRecorder(), r)
+	if deferred {
+		t.Error("Expected deferred functions to wait for the request to be cleared")
+	}
+	Clear(r)
+	if !deferred {
+		t.Error("Expected deferred functions to run when the request is cleared")
+	}
+
+	r2, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r2)
+	Set(r2, key1, "1")
+	LogContext(http.HandlerFunc(func(w http.ResponseWriter, in *http.Request) {
+		Delete(in, key1)
+		c := Child(in)
+		defer Clear(c)
+		Set(c, key2, "child")
+	})).ServeHTTP(httptest.NewRecorder(), r2)
+	if Has(r2, key1) || Has(r2, key2) {
+		t.Errorf("Expected the deletion to stick and child values to stay apart, got %v", GetAll(r2))
+	}
+}
+
+func TestAlias(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	in := Alias(r)
+	if RequestFromContext(in.Context()) != in || Alias(in).Context().Value(requestKey{}).(*requestHolder).orig != r {
+		t.Error("Expected aliases of aliases to share the values of r")
+	}
+	Set(in, key1, "1")
+	if Get(r, key1) != "1" {
+		t.Error("Expected values set through the alias to be those of r")
+	}
+	Clear(in)
+	if _, ok := GetAllOk(r); ok {
+		t.Error("Expected clearing the alias to clear r")
+	}
+}
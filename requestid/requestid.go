@@ -0,0 +1,123 @@
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package requestid assigns an ID to every request, stored with
+// gorilla/context:
+//
+//	http.Handle("/", context.ClearHandler(requestid.Handler(mux)))
+//
+//	// In a handler:
+//	log.Printf("%s: done", requestid.Get(r))
+//
+// The ID is taken from the X-Request-ID header if the client sent a valid
+// one, and generated otherwise.
+package requestid
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/context"
+)
+
+// DefaultHeader is the header the ID is read from by default.
+const DefaultHeader = "X-Request-ID"
+
+// maxLen is the maximum length of the IDs accepted from clients.
+const maxLen = 128
+
+type key int
+
+const idKey key = 0
+
+func init() {
+	context.AllowKey(idKey)
+}
+
+// Option configures Handler.
+type Option func(*options)
+
+type options struct {
+	header   string
+	echo     bool
+	generate func() string
+}
+
+// WithHeader reads IDs from, and echoes them in, the given header instead of
+// DefaultHeader.
+func WithHeader(name string) Option {
+	return func(o *options) {
+		o.header = name
+	}
+}
+
+// WithEcho sets the ID in the response header, so clients can report it.
+func WithEcho() Option {
+	return func(o *options) {
+		o.echo = true
+	}
+}
+
+// WithGenerator generates IDs with f instead of NewID.
+func WithGenerator(f func() string) Option {
+	return func(o *options) {
+		o.generate = f
+	}
+}
+
+// Handler wraps h to store an ID for every request, found with Get.
+func Handler(h http.Handler, opts ...Option) http.Handler {
+	o := options{header: DefaultHeader, generate: NewID}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(o.header)
+		if !valid(id) {
+			id = o.generate()
+		}
+		Set(r, id)
+		if o.echo {
+			w.Header().Set(o.header, id)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// Get returns the ID of a request, or "" if it has none.
+func Get(r *http.Request) string {
+	id, _ := context.Get(r, idKey).(string)
+	return id
+}
+
+// Set stores the ID of a request.
+func Set(r *http.Request, id string) {
+	context.Set(r, idKey, id)
+}
+
+// NewID returns a random version 4 UUID.
+func NewID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic("requestid: " + err.Error())
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// valid reports whether an ID sent by a client can be used: it must be made
+// of printable ASCII characters, so that it can't forge log lines.
+func valid(id string) bool {
+	if id == "" || len(id) > maxLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/gorilla/context"
+)
+
+var uuid = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func serve(h http.Handler, header string) (id string, w *httptest.ResponseRecorder) {
+	r := httptest.NewRequest("GET", "/", nil)
+	if header != "" {
+		r.Header.Set(DefaultHeader, header)
+	}
+	w = httptest.NewRecorder()
+	context.ClearHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r)
+		id = Get(r)
+	})).ServeHTTP(w, r)
+	return id, w
+}
+
+func TestHandler(t *testing.T) {
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	if id, _ := serve(h, "abc-123"); id != "abc-123" {
+		t.Errorf("Expected the client ID, got %q", id)
+	}
+	id, w := serve(h, "")
+	if !uuid.MatchString(id) {
+		t.Errorf("Expected a generated UUID, got %q", id)
+	}
+	if w.Header().Get(DefaultHeader) != "" {
+		t.Error("Expected the ID not to be echoed by default")
+	}
+	if id, _ := serve(h, "bad\nid"); id == "bad\nid" || !uuid.MatchString(id) {
+		t.Errorf("Expected an invalid ID to be replaced, got %q", id)
+	}
+}
+
+func TestHandlerOptions(t *testing.T) {
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		WithEcho(), WithHeader("X-Trace"), WithGenerator(func() string { return "gen" }))
+
+	id, w := serve(h, "ignored")
+	if id != "gen" {
+		t.Errorf("Expected a generated ID, got %q", id)
+	}
+	if got := w.Header().Get("X-Trace"); got != "gen" {
+		t.Errorf("Expected the ID to be echoed, got %q", got)
+	}
+}
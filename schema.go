@@ -0,0 +1,72 @@
+package context
+
+import (
+	"errors"
+	"reflect"
+	"sync/atomic"
+)
+
+// ErrTypeMismatch is returned by SetE for values whose type doesn't match
+// the type registered for their key with RegisterKey.
+var ErrTypeMismatch = errors.New("context: value type doesn't match the registered type")
+
+var (
+	// typedKeys is set once a key is registered: it is read by every Set,
+	// so it is accessed atomically.
+	typedKeys int64
+	// keyTypes holds the types registered with RegisterKey, as a
+	// map[interface{}]reflect.Type replaced on every change.
+	keyTypes atomic.Value
+)
+
+// RegisterKey registers the type of the values stored under key. Set then
+// refuses values that are not assignable to t, and SetE returns
+// ErrTypeMismatch, so that type errors are found where values are stored
+// rather than where they are read. Nil values are accepted for types that
+// can be nil.
+//
+// The key is also allowed by SetKeyPolicy. RegisterKey is meant to be called
+// during program initialization:
+//
+//	context.RegisterKey(userKey, reflect.TypeOf((*User)(nil)))
+func RegisterKey(key interface{}, t reflect.Type) {
+	mutex.Lock()
+	old, _ := keyTypes.Load().(map[interface{}]reflect.Type)
+	m := make(map[interface{}]reflect.Type, len(old)+1)
+	for k, t := range old {
+		m[k] = t
+	}
+	m[key] = t
+	keyTypes.Store(m)
+	atomic.StoreInt64(&typedKeys, 1)
+	mutex.Unlock()
+	AllowKey(key)
+}
+
+// checkType checks a new value against the type registered for its key.
+func checkType(key, val interface{}) error {
+	if loadInt(&typedKeys) == 0 {
+		return nil
+	}
+	m, _ := keyTypes.Load().(map[interface{}]reflect.Type)
+	t, ok := m[key]
+	if !ok {
+		return nil
+	}
+	if _, ok := val.(*lazyValue); ok {
+		// Not computed yet.
+		return nil
+	}
+	vt := reflect.TypeOf(unbox(val))
+	if vt == nil {
+		switch t.Kind() {
+		case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+			return nil
+		}
+		return ErrTypeMismatch
+	}
+	if !vt.AssignableTo(t) {
+		return ErrTypeMismatch
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package context
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRegisterKey(t *testing.T) {
+	RegisterKey("count", reflect.TypeOf(0))
+	RegisterKey("name", reflect.TypeOf((*fmt.Stringer)(nil)).Elem())
+	defer func() {
+		keyTypes.Store(map[interface{}]reflect.Type(nil))
+		atomic.StoreInt64(&typedKeys, 0)
+	}()
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	for _, tt := range []struct {
+		key, val interface{}
+		want     error
+	}{
+		{"count", 1, nil},
+		{"count", "1", ErrTypeMismatch},
+		{"count", nil, ErrTypeMismatch},
+		{"name", NewKey[int]("name"), nil},
+		{"name", nil, nil},
+		{"name", 1, ErrTypeMismatch},
+		{"other", "1", nil},
+	} {
+		if err := SetE(r, tt.key, tt.val); err != tt.want {
+			t.Errorf("SetE(%v, %#v): expected %v, got %v", tt.key, tt.val, tt.want, err)
+		}
+	}
+	SetS(r, "count", "2")
+	if Get(r, "count") != 1 {
+		t.Errorf("Expected SetS to refuse the value, got %v", Get(r, "count"))
+	}
+}
@@ -0,0 +1,38 @@
+package context
+
+// Redacted replaces the values of secret keys in reports.
+const Redacted = "[redacted]"
+
+// secretKeys holds the keys marked with MarkSecret.
+var secretKeys map[interface{}]struct{}
+
+// MarkSecret marks the values stored under key as secret, such as tokens
+// and passwords: they are replaced with Redacted by Dump, Diff,
+// MarshalJSON, LogValue and NewLogHandler, and by the log and tracing
+// adapters of the subpackages. It is meant to be called during program
+// initialization.
+func MarkSecret(key interface{}) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if secretKeys == nil {
+		secretKeys = make(map[interface{}]struct{})
+	}
+	secretKeys[key] = struct{}{}
+}
+
+// IsSecret reports whether key was marked with MarkSecret.
+func IsSecret(key interface{}) bool {
+	mutex.RLock()
+	_, ok := secretKeys[key]
+	mutex.RUnlock()
+	return ok
+}
+
+// Redact returns Redacted if key was marked with MarkSecret, and val
+// otherwise. Code reporting request values calls it before writing them.
+func Redact(key, val interface{}) interface{} {
+	if IsSecret(key) {
+		return Redacted
+	}
+	return val
+}
@@ -0,0 +1,39 @@
+package context
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMarkSecret(t *testing.T) {
+	MarkSecret("token")
+	defer func() {
+		mutex.Lock()
+		delete(secretKeys, "token")
+		mutex.Unlock()
+	}()
+	if !IsSecret("token") || IsSecret(key1) {
+		t.Fatal("Unexpected secret keys")
+	}
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	Set(r, "token", "hunter2")
+	Set(r, "user", "gopher")
+	before := GetAll(r)
+	if Get(r, "token") != "hunter2" {
+		t.Error("Expected secret values to be readable")
+	}
+	if d := Dump(r); strings.Contains(d, "hunter2") || !strings.Contains(d, "token (string) = [redacted] (string)") {
+		t.Errorf("Expected Dump to redact the token, got %q", d)
+	}
+	Set(r, "token", "hunter3")
+	if d := Diff(before, GetAll(r)); strings.Contains(d, "hunter") {
+		t.Errorf("Expected Diff to redact the token, got %q", d)
+	}
+	b, _ := MarshalJSON(r)
+	if s := string(b); s != `{"token":"[redacted]","user":"gopher"}` {
+		t.Errorf("Expected MarshalJSON to redact the token, got %s", s)
+	}
+}
@@ -0,0 +1,215 @@
+package context
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrMemoryLimit is returned by SetE when storing a value would exceed the
+// limit configured with SetMemoryLimit.
+var ErrMemoryLimit = errors.New("context: memory limit reached")
+
+// ErrValueTooLarge is returned by SetE for values larger than the limit
+// configured with SetMaxValueSize.
+var ErrValueTooLarge = errors.New("context: value too large")
+
+// Sizer is implemented by values that know their approximate size in bytes.
+// Values that don't implement it are measured using reflection.
+type Sizer interface {
+	Size() int
+}
+
+var (
+	memLimit     int64
+	maxValueSize int64
+	// memMu guards memTotal, the size of all accounted values.
+	memMu    sync.Mutex
+	memTotal int
+)
+
+// SetMemoryLimit sets a process-wide limit, in bytes, on the approximate
+// size of all stored values. When a Set would exceed it, entries of the same
+// request are removed according to the EvictionPolicy; if that is not enough,
+// or the policy is RejectNew, the value is refused and SetE returns
+// ErrMemoryLimit.
+//
+// If n <= 0, which is the default, memory is not accounted. Only values
+// stored while a limit is set count against it. Values stored by the package
+// for its own bookkeeping, such as the user or the feature flags, don't
+// count and are never removed.
+func SetMemoryLimit(n int) {
+	atomic.StoreInt64(&memLimit, int64(n))
+}
+
+// SetMaxValueSize refuses values larger than n bytes, as measured for
+// SetMemoryLimit: SetE returns ErrValueTooLarge instead of storing them.
+// This protects servers from middleware storing whole request bodies or
+// large decoded documents by mistake.
+//
+// If n <= 0, which is the default, the size of values is not limited.
+func SetMaxValueSize(n int) {
+	atomic.StoreInt64(&maxValueSize, int64(n))
+}
+
+// checkSize applies the limit of SetMaxValueSize to a new value.
+func checkSize(val interface{}) error {
+	if max := loadInt(&maxValueSize); max > 0 && int64(sizeOf(val)) > max {
+		return ErrValueTooLarge
+	}
+	return nil
+}
+
+// MemoryUsage returns the approximate size in bytes of the values stored
+// for a given request. Values set with SetLazy count once computed.
+func MemoryUsage(r *http.Request) int {
+	total := 0
+	vs, s := view(r)
+	if vs == nil {
+		return 0
+	}
+	vs.each(func(_, v interface{}) bool {
+		total += sizeOf(v)
+		return true
+	})
+	if s != nil {
+		s.mu.RUnlock()
+	}
+	return total
+}
+
+func addMemory(n int) {
+	if n != 0 {
+		memMu.Lock()
+		memTotal += n
+		memMu.Unlock()
+	}
+}
+
+// account checks a new value against memLimit and records its size. It
+// must be called with the store locked for writing.
+func (s *Store) account(key, val interface{}) error {
+	size := sizeOf(val)
+	old := s.sizes[key]
+	for {
+		limit := int(loadInt(&memLimit))
+		memMu.Lock()
+		if memTotal-old+size <= limit {
+			memTotal += size - old
+			memMu.Unlock()
+			break
+		}
+		// Don't evict anything if that can't make enough room.
+		feasible := memTotal-s.accounted()+size <= limit
+		memMu.Unlock()
+		if !feasible || EvictionPolicy(loadInt(&evictPolicy)) == RejectNew || !s.evictLRU(key) {
+			return ErrMemoryLimit
+		}
+	}
+	if s.sizes == nil {
+		s.sizes = make(map[interface{}]int)
+	}
+	s.sizes[key] = size
+	return nil
+}
+
+// accounted returns the accounted size of the values of the store.
+func (s *Store) accounted() int {
+	total := 0
+	for _, size := range s.sizes {
+		total += size
+	}
+	return total
+}
+
+// sizeOf returns the approximate size of v in bytes.
+func sizeOf(v interface{}) int {
+	if s, ok := v.(Sizer); ok {
+		return s.Size()
+	}
+	if v == nil {
+		return 0
+	}
+	return estimate(reflect.ValueOf(v), make(map[uintptr]bool))
+}
+
+// estimate walks a value and adds up the memory it references. Values
+// reachable through several pointers are counted once.
+func estimate(v reflect.Value, seen map[uintptr]bool) int {
+	size := int(v.Type().Size())
+	switch v.Kind() {
+	case reflect.String:
+		size += v.Len()
+	case reflect.Slice:
+		if v.IsNil() || seen[v.Pointer()] {
+			break
+		}
+		seen[v.Pointer()] = true
+		if et := v.Type().Elem(); pointerFree(et) {
+			size += v.Len() * int(et.Size())
+			break
+		}
+		for i := 0; i < v.Len(); i++ {
+			size += estimate(v.Index(i), seen)
+		}
+	case reflect.Array:
+		if pointerFree(v.Type().Elem()) {
+			break
+		}
+		size = 0
+		for i := 0; i < v.Len(); i++ {
+			size += estimate(v.Index(i), seen)
+		}
+	case reflect.Map:
+		if v.IsNil() || seen[v.Pointer()] {
+			break
+		}
+		seen[v.Pointer()] = true
+		iter := v.MapRange()
+		for iter.Next() {
+			size += estimate(iter.Key(), seen) + estimate(iter.Value(), seen)
+		}
+	case reflect.Ptr:
+		if v.IsNil() || seen[v.Pointer()] {
+			break
+		}
+		seen[v.Pointer()] = true
+		size += estimate(v.Elem(), seen)
+	case reflect.Interface:
+		if !v.IsNil() {
+			size += estimate(v.Elem(), seen)
+		}
+	case reflect.Struct:
+		size = 0
+		for i := 0; i < v.NumField(); i++ {
+			size += estimate(v.Field(i), seen)
+		}
+		if min := int(v.Type().Size()); size < min {
+			size = min
+		}
+	}
+	return size
+}
+
+// pointerFree reports whether values of type t reference no other memory,
+// so that their size is that of the type.
+func pointerFree(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	case reflect.Array:
+		return pointerFree(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if !pointerFree(t.Field(i).Type) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package context
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type fixedSize int
+
+func (s fixedSize) Size() int {
+	return int(s)
+}
+
+func TestMemoryUsage(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+
+	if n := MemoryUsage(r); n != 0 {
+		t.Errorf("Expected 0 for an empty request, got %d", n)
+	}
+	Set(r, key1, fixedSize(100))
+	if n := MemoryUsage(r); n != 100 {
+		t.Errorf("Expected 100, got %d", n)
+	}
+	Set(r, key2, strings.Repeat("x", 1000))
+	if n := MemoryUsage(r); n < 1100 {
+		t.Errorf("Expected at least 1100, got %d", n)
+	}
+
+	// Cyclic values must not loop forever.
+	type node struct{ next *node }
+	n := &node{}
+	n.next = n
+	Set(r, "cycle", n)
+	MemoryUsage(r)
+}
+
+func TestSizeOfScalars(t *testing.T) {
+	type point struct{ X, Y int32 }
+	for _, tt := range []struct {
+		v    interface{}
+		want int
+	}{
+		{make([]byte, 1<<20), 24 + 1<<20},
+		{make([]point, 10), 24 + 80},
+		{[4]int64{}, 32},
+		{[]string{"ab", "c"}, 24 + 2*16 + 3},
+	} {
+		if got := sizeOf(tt.v); got != tt.want {
+			t.Errorf("sizeOf(%T) = %d, want %d", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryLimit(t *testing.T) {
+	SetMemoryLimit(250)
+	defer SetMemoryLimit(0)
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+
+	Set(r, "a", fixedSize(100))
+	Set(r, "b", fixedSize(100))
+	Get(r, "a")
+	// "b" is the least recently used entry and makes room for "c".
+	if err := SetE(r, "c", fixedSize(100)); err != nil {
+		t.Fatalf("SetE returned %v", err)
+	}
+	if _, ok := GetOk(r, "b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	if err := SetE(r, "d", fixedSize(300)); err != ErrMemoryLimit {
+		t.Errorf("Expected ErrMemoryLimit, got %v", err)
+	}
+	if storeLen(r) != 2 {
+		t.Errorf("Expected a refused value to evict nothing, got %v", GetAll(r))
+	}
+
+	Clear(r)
+	if memTotal != 0 {
+		t.Errorf("Expected no memory in use after Clear, got %d", memTotal)
+	}
+}
+
+func TestMaxValueSize(t *testing.T) {
+	SetMaxValueSize(100)
+	defer SetMaxValueSize(0)
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	if err := SetE(r, "small", fixedSize(100)); err != nil {
+		t.Errorf("SetE returned %v", err)
+	}
+	if err := SetE(r, "body", fixedSize(101)); err != ErrValueTooLarge {
+		t.Errorf("Expected ErrValueTooLarge, got %v", err)
+	}
+	SetS(r, "blob", make([]byte, 1000))
+	if _, ok := GetOk(r, "blob"); ok {
+		t.Error("Expected SetS to refuse the value")
+	}
+}
+
+func BenchmarkSizeOfBytes(b *testing.B) {
+	v := make([]byte, 1<<20)
+	for i := 0; i < b.N; i++ {
+		sizeOf(v)
+	}
+}
+
+func TestMemoryLimitInternal(t *testing.T) {
+	SetMemoryLimit(150)
+	defer SetMemoryLimit(0)
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+
+	SetUser(r, "admin")
+	SetTenant(r, "acme")
+	Set(r, "a", fixedSize(100))
+	if err := SetE(r, "b", fixedSize(100)); err != nil {
+		t.Fatalf("SetE returned %v", err)
+	}
+	if !IsAuthenticated(r) || Tenant(r) != "acme" {
+		t.Error("Expected the internal entries not to be evicted")
+	}
+	if Has(r, "a") {
+		t.Error("Expected a to be evicted")
+	}
+	if memTotal != 100 {
+		t.Errorf("Expected only application values to be accounted, got %d", memTotal)
+	}
+}
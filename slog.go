@@ -0,0 +1,79 @@
+//go:build go1.21
+
+package context
+
+import (
+	stdcontext "context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+)
+
+// LogKey names the log attribute a request value is added as.
+type LogKey struct {
+	Key  interface{}
+	Name string
+}
+
+// LogValue returns the values stored for a request as a slog group, with
+// keys formatted by fmt.Sprint and secret values redacted, so that a request
+// can be logged with:
+//
+//	slog.Info("done", "context", context.LogValue(r))
+func LogValue(r *http.Request) slog.Value {
+	values := GetAll(r)
+	attrs := make([]slog.Attr, 0, len(values))
+	for k, v := range values {
+		attrs = append(attrs, slog.Any(fmt.Sprint(k), Redact(k, v)))
+	}
+	sort.Slice(attrs, func(i, j int) bool {
+		return attrs[i].Key < attrs[j].Key
+	})
+	return slog.GroupValue(attrs...)
+}
+
+// NewLogHandler returns a slog.Handler adding the values stored for keys to
+// every record logged with the context of a request, then passing it to h.
+// Requests are found from their context if they went through LogContext:
+//
+//	slog.SetDefault(slog.New(context.NewLogHandler(handler,
+//		context.LogKey{Key: requestIDKey, Name: "request_id"})))
+//	http.Handle("/", context.ClearHandler(context.LogContext(mux)))
+//
+//	// In a handler:
+//	slog.InfoContext(r.Context(), "done")
+//
+// Values that are not stored are skipped, and values marked with MarkSecret
+// are redacted.
+func NewLogHandler(h slog.Handler, keys ...LogKey) slog.Handler {
+	return &logHandler{h: h, keys: keys}
+}
+
+type logHandler struct {
+	h    slog.Handler
+	keys []LogKey
+}
+
+func (h *logHandler) Enabled(ctx stdcontext.Context, level slog.Level) bool {
+	return h.h.Enabled(ctx, level)
+}
+
+func (h *logHandler) Handle(ctx stdcontext.Context, rec slog.Record) error {
+	if r := RequestFromContext(ctx); r != nil {
+		for _, k := range h.keys {
+			if v, ok := GetOk(r, k.Key); ok {
+				rec.AddAttrs(slog.Any(k.Name, Redact(k.Key, v)))
+			}
+		}
+	}
+	return h.h.Handle(ctx, rec)
+}
+
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &logHandler{h: h.h.WithAttrs(attrs), keys: h.keys}
+}
+
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	return &logHandler{h: h.h.WithGroup(name), keys: h.keys}
+}
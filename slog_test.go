@@ -0,0 +1,57 @@
+//go:build go1.21
+
+package context
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLogValue(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	Set(r, "b", 2)
+	Set(r, "a", "1")
+
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("done", "context", LogValue(r))
+	if out := buf.String(); !strings.Contains(out, "context.a=1 context.b=2") {
+		t.Errorf("Unexpected output %q", out)
+	}
+}
+
+func TestLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewLogHandler(slog.NewTextHandler(&buf, nil),
+		LogKey{key1, "request_id"}, LogKey{key2, "user"}))
+
+	h := LogContext(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Set(r, key2, "gopher")
+		logger.InfoContext(r.Context(), "handled")
+	}))
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	Set(r, key1, "42")
+	ClearHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r)
+		if Get(r, key2) != "gopher" {
+			t.Error("Expected values stored by the handler to be moved back")
+		}
+	})).ServeHTTP(httptest.NewRecorder(), r)
+
+	if out := buf.String(); !strings.Contains(out, "request_id=42 user=gopher") {
+		t.Errorf("Unexpected output %q", out)
+	}
+	if n := storeCount(); n != 0 {
+		t.Errorf("Expected no stores left, got %d", n)
+	}
+
+	buf.Reset()
+	logger.Info("outside")
+	if out := buf.String(); strings.Contains(out, "request_id") {
+		t.Errorf("Unexpected output %q", out)
+	}
+}
@@ -0,0 +1,125 @@
+package context
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// KeyStats counts the lookups of a key.
+type KeyStats struct {
+	// Hits is the number of lookups that found a value.
+	Hits uint64
+	// Misses is the number of lookups that found none.
+	Misses uint64
+}
+
+var (
+	// stats is read by every Get, so it is accessed atomically instead of
+	// under the package lock.
+	stats int64
+	// keyStats maps keys to *KeyStats, updated atomically.
+	keyStats sync.Map
+	// keyStatsLen is the number of keys in keyStats.
+	keyStatsLen int64
+)
+
+// maxKeyStats bounds the number of keys counted, so that applications
+// using keys built per request, such as strings holding ids, don't grow
+// keyStats without bound. Lookups of other keys are not counted.
+const maxKeyStats = 1024
+
+// EnableStats turns the counting of lookups per key on or off. It is off by
+// default, so that lookups don't pay for it. Counts are kept when it is
+// turned off.
+//
+// Lookups by Get, GetOk, GetS and Key.Get are counted, across all requests,
+// for up to 1024 distinct keys; ResetStats drops the counts.
+func EnableStats(on bool) {
+	if on {
+		atomic.StoreInt64(&stats, 1)
+	} else {
+		atomic.StoreInt64(&stats, 0)
+	}
+}
+
+// Stats returns the lookup counts of every key looked up while stats were
+// enabled, by key name, as formatted by DebugHandler. Keys never found are
+// likely stored by nobody anymore, and keys often found are worth
+// computing once per request. The counts of distinct keys with the same
+// name, such as two Keys created with the same name, are added up.
+func Stats() map[string]KeyStats {
+	m := make(map[string]KeyStats)
+	keyStats.Range(func(k, v interface{}) bool {
+		ks := v.(*KeyStats)
+		name := keyName(k)
+		sum := m[name]
+		sum.Hits += atomic.LoadUint64(&ks.Hits)
+		sum.Misses += atomic.LoadUint64(&ks.Misses)
+		m[name] = sum
+		return true
+	})
+	return m
+}
+
+// ResetStats drops the lookup counts of every key, making room for keys
+// not counted since the limit was reached.
+func ResetStats() {
+	keyStats.Range(func(k, _ interface{}) bool {
+		if _, ok := keyStats.LoadAndDelete(k); ok {
+			atomic.AddInt64(&keyStatsLen, -1)
+		}
+		return true
+	})
+}
+
+// countGet counts a lookup of key if stats are enabled.
+func countGet(key interface{}, ok bool) {
+	if loadInt(&stats) == 0 {
+		return
+	}
+	v, found := keyStats.Load(key)
+	if !found {
+		if atomic.LoadInt64(&keyStatsLen) >= maxKeyStats {
+			return
+		}
+		var loaded bool
+		if v, loaded = keyStats.LoadOrStore(key, new(KeyStats)); !loaded {
+			atomic.AddInt64(&keyStatsLen, 1)
+		}
+	}
+	ks := v.(*KeyStats)
+	if ok {
+		atomic.AddUint64(&ks.Hits, 1)
+	} else {
+		atomic.AddUint64(&ks.Misses, 1)
+	}
+}
+
+// countGetS is countGet for string keys, only converting the key to
+// interface{} if needed.
+func countGetS(key string, ok bool) {
+	if loadInt(&stats) != 0 {
+		countGet(key, ok)
+	}
+}
+
+// getCounted is GetOk without tracing, counting the lookup.
+func getCounted(r *http.Request, key interface{}) (interface{}, bool) {
+	var (
+		v  interface{}
+		ok bool
+	)
+	for p := r; p != nil; p = parentFor(p, key) {
+		if s := lookup(p); s != nil {
+			if v, ok = s.get(p, key); ok {
+				break
+			}
+		}
+	}
+	if !ok {
+		v, ok = getDefault(key)
+	}
+	countGet(key, ok)
+	return v, ok
+}
@@ -0,0 +1,59 @@
+package context
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+
+	Get(r, "uncounted")
+	EnableStats(true)
+	defer EnableStats(false)
+
+	Set(r, "hot", 1)
+	k := NewKey[int]("typed")
+	k.Set(r, 1)
+	Get(r, "hot")
+	GetS(r, "hot")
+	GetOk(r, "dead")
+	k.Get(r)
+	// Another key with the same name: its counts are added up.
+	NewKey[int]("typed").Get(r)
+
+	stats := Stats()
+	if s := stats["hot (string)"]; s.Hits != 2 || s.Misses != 0 {
+		t.Errorf("Unexpected stats for hot: %+v", s)
+	}
+	if s := stats["dead (string)"]; s.Hits != 0 || s.Misses != 1 {
+		t.Errorf("Unexpected stats for dead: %+v", s)
+	}
+	if s := stats["typed (*context.Key[int])"]; s.Hits != 1 || s.Misses != 1 {
+		t.Errorf("Unexpected stats for typed: %+v (%v)", s, stats)
+	}
+	if _, ok := stats["uncounted (string)"]; ok {
+		t.Error("Expected lookups before EnableStats not to be counted")
+	}
+}
+
+func TestStatsLimit(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	ResetStats()
+	defer ResetStats()
+	EnableStats(true)
+	defer EnableStats(false)
+
+	for i := 0; i < maxKeyStats+10; i++ {
+		Get(r, i)
+	}
+	if n := len(Stats()); n != maxKeyStats {
+		t.Errorf("Expected %d counted keys, got %d", maxKeyStats, n)
+	}
+	ResetStats()
+	Get(r, "after")
+	if stats := Stats(); len(stats) != 1 || stats["after (string)"].Misses != 1 {
+		t.Errorf("Unexpected stats after ResetStats: %v", stats)
+	}
+}
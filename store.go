@@ -0,0 +1,348 @@
+package context
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// Store holds the values of a single request.
+//
+// Stores are associated with requests by the Backend; each store has its own
+// lock, so that goroutines spawned by a handler can safely share a request
+// and requests don't contend with each other. The contents of a store are
+// only accessible through the package functions.
+type Store struct {
+	mu     sync.RWMutex
+	values valueSet
+	// created is the creation time of the store, in Unix nanoseconds.
+	created int64
+	// used holds the last use of every key, for EvictLRU.
+	used map[interface{}]*uint64
+	// sizes holds the size of every value accounted against memLimit.
+	sizes map[interface{}]int
+	// deferred holds the functions registered with Defer.
+	deferred []func()
+	// history holds the changes recorded for History.
+	history map[interface{}][]Mutation
+	// cleared is set once the store was released by the backend. Writers
+	// that looked it up before must look it up again.
+	cleared bool
+	// detached is set by TransferToConn: ClearHandler leaves the store to
+	// the connection.
+	detached bool
+	// frozen is set by Freeze: the values can't be changed anymore.
+	frozen bool
+	// parent is the request values are inherited from, set by Child.
+	parent *http.Request
+	// hidden holds the keys deleted from a child store, which are no longer
+	// read from the parent.
+	hidden map[interface{}]struct{}
+	// layers hold the values replaced since every PushLayer.
+	layers []layer
+	// watchers hold the channels registered with Watch, by key.
+	watchers map[interface{}][]*watcher
+	// holdPublish is set by Txn while it applies its changes: they are
+	// published at once.
+	holdPublish bool
+	// owner is the request of a store that can be recycled. Callers that
+	// looked up a store must check it, since it may have been recycled for
+	// another request in the meantime.
+	owner *http.Request
+	// snap is the published copy of values in copy-on-write mode.
+	snap atomic.Pointer[snapshot]
+	// stripes hold the values instead of values in striped mode.
+	stripes []stripe
+}
+
+// storePool holds cleared stores for reuse by newStoreFor.
+var storePool = sync.Pool{
+	New: func() interface{} {
+		return new(Store)
+	},
+}
+
+// NewStore returns an empty store, for use by Backend implementations.
+func NewStore() *Store {
+	s := &Store{
+		created: now().UnixNano(),
+	}
+	s.initSnapshot()
+	s.initStripes()
+	return s
+}
+
+// newStoreFor returns an empty store owned by r, recycled when possible.
+// The backends of this package use it instead of NewStore.
+func newStoreFor(r *http.Request) *Store {
+	s := storePool.Get().(*Store)
+	s.mu.Lock()
+	s.created = now().UnixNano()
+	s.cleared = false
+	s.detached = false
+	s.frozen = false
+	s.parent = nil
+	s.hidden = nil
+	s.layers = nil
+	s.watchers = nil
+	s.owner = r
+	s.initSnapshot()
+	s.initStripes()
+	s.mu.Unlock()
+	return s
+}
+
+// recycle puts a cleared store back in the pool.
+func recycle(s *Store) {
+	s.mu.Lock()
+	reusable := s.owner != nil && s.cleared
+	s.mu.Unlock()
+	// Stores not created by newStoreFor, or already reused, are left alone.
+	if reusable {
+		storePool.Put(s)
+	}
+}
+
+// ownedBy reports whether the store still belongs to r. It must be called
+// with the store locked.
+func (s *Store) ownedBy(r *http.Request) bool {
+	return s.owner == nil || s.owner == r || s.owner == aliasOf(r)
+}
+
+// lookup returns the store of a request, or nil if it has none. Requests
+// made with Alias have the store of the request they share values with.
+func lookup(r *http.Request) *Store {
+	b := currentBackend()
+	s := b.Lookup(r)
+	if s == nil {
+		if o := aliasOf(r); o != nil {
+			s = b.Lookup(o)
+		}
+	}
+	return s
+}
+
+// view returns the values of a request for reading, or nil if it has none.
+// If locked is not nil, it is the store the values belong to, locked for
+// reading, and the caller must unlock it once done. Otherwise the values are
+// an immutable copy-on-write snapshot.
+func view(r *http.Request) (vs *valueSet, locked *Store) {
+	s := lookup(r)
+	if s == nil {
+		return nil, nil
+	}
+	if snap := s.snap.Load(); snap != nil {
+		if !snap.ownedBy(r) {
+			return nil, nil
+		}
+		return &snap.values, nil
+	}
+	s.mu.RLock()
+	if !s.ownedBy(r) {
+		s.mu.RUnlock()
+		return nil, nil
+	}
+	if s.stripes != nil {
+		vs := s.merged()
+		s.mu.RUnlock()
+		return &vs, nil
+	}
+	return &s.values, s
+}
+
+// get returns the value stored for key if the store belongs to r.
+func (s *Store) get(r *http.Request, key interface{}) (interface{}, bool) {
+	if snap := s.snap.Load(); snap != nil {
+		if !snap.ownedBy(r) {
+			return nil, false
+		}
+		return snap.values.get(key)
+	}
+	s.mu.RLock()
+	if !s.ownedBy(r) {
+		s.mu.RUnlock()
+		return nil, false
+	}
+	value, ok := s.getKey(key)
+	if ok && trackUse() {
+		s.touch(key)
+	}
+	s.mu.RUnlock()
+	return value, ok
+}
+
+// has reports whether a value is stored for key if the store belongs to r.
+func (s *Store) has(r *http.Request, key interface{}) bool {
+	if snap := s.snap.Load(); snap != nil {
+		if !snap.ownedBy(r) {
+			return false
+		}
+		_, ok := snap.values.getRaw(key)
+		return ok
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.ownedBy(r) {
+		return false
+	}
+	_, ok := s.getRawKey(key)
+	return ok
+}
+
+// writeLocked returns the store of a request locked for writing, or nil if
+// it has none.
+func writeLocked(r *http.Request) *Store {
+	s := lookup(r)
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	if !s.ownedBy(r) {
+		s.mu.Unlock()
+		return nil
+	}
+	return s
+}
+
+// attach returns the store of a request, creating it if needed. The store
+// is returned locked for writing.
+func attach(r *http.Request) *Store {
+	if o := aliasOf(r); o != nil && currentBackend().Lookup(r) == nil {
+		r = o
+	}
+	for {
+		s, created := currentBackend().Attach(r)
+		if created {
+			if loadInt(&strict) != 0 {
+				watchLeak(r)
+			}
+			notifyCreated(currentListeners(), r)
+		}
+		s.mu.Lock()
+		if !s.cleared && s.ownedBy(r) {
+			return s
+		}
+		// Cleared concurrently: start over with a new store.
+		s.mu.Unlock()
+	}
+}
+
+// plainWrites reports whether writes only need to store the value, so that
+// fast paths may skip set.
+func plainWrites() bool {
+	return loadInt(&maxEntries) <= 0 && loadInt(&memLimit) <= 0 &&
+		loadInt(&maxValueSize) <= 0 && loadInt(&keyPolicy) == 0 && loadInt(&typedKeys) == 0 &&
+		loadInt(&writeOnceKeys) == 0
+}
+
+// set stores a value, applying the configured limits. It must be called
+// with the store locked for writing.
+func (s *Store) set(key, val interface{}) error {
+	if err := s.writable(key); err != nil {
+		return err
+	}
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	if err := checkSize(val); err != nil {
+		return err
+	}
+	if err := checkType(key, val); err != nil {
+		return err
+	}
+	if err := s.checkOverwrite(key); err != nil {
+		return err
+	}
+	if s.layers != nil {
+		s.saveForLayer(key)
+	}
+	if max := int(loadInt(&maxEntries)); max > 0 && !isInternal(key) {
+		if _, ok := s.getRawKey(key); !ok && s.countApp() >= max {
+			if EvictionPolicy(loadInt(&evictPolicy)) == RejectNew {
+				return ErrMaxEntries
+			}
+			s.evictLRU(key)
+		}
+	}
+	if loadInt(&memLimit) > 0 && !isInternal(key) {
+		if err := s.account(key, val); err != nil {
+			return err
+		}
+	}
+	s.putKey(key, val)
+	delete(s.hidden, key)
+	if trackUse() {
+		s.touchNew(key)
+	}
+	s.publish()
+	if s.watchers != nil {
+		s.notifyWatchers(key, unbox(val))
+	}
+	return nil
+}
+
+// remove deletes a value. It must be called with the store locked for
+// writing.
+func (s *Store) remove(key interface{}) error {
+	if err := s.writable(key); err != nil {
+		return err
+	}
+	if s.layers != nil {
+		s.saveForLayer(key)
+	}
+	s.delKey(key)
+	if s.parent != nil {
+		if s.hidden == nil {
+			s.hidden = make(map[interface{}]struct{})
+		}
+		s.hidden[key] = struct{}{}
+	}
+	delete(s.used, key)
+	if size, ok := s.sizes[key]; ok {
+		addMemory(-size)
+		delete(s.sizes, key)
+	}
+	s.publish()
+	if s.watchers != nil {
+		s.notifyWatchers(key, nil)
+	}
+	return nil
+}
+
+// clear marks the store as cleared and returns what it held, to be handled
+// by the caller.
+func (s *Store) clear() cleared {
+	s.mu.Lock()
+	addMemory(-s.accounted())
+	c := cleared{values: s.values, deferred: s.deferred}
+	if s.stripes != nil {
+		c.values = s.merged()
+	}
+	s.values = valueSet{}
+	for i := range s.stripes {
+		s.stripes[i].values = valueSet{}
+	}
+	s.used, s.sizes, s.deferred, s.history = nil, nil, nil, nil
+	s.cleared = true
+	s.detached = false
+	s.frozen = false
+	if s.parent != nil {
+		atomic.AddInt64(&children, -1)
+		s.parent, s.hidden = nil, nil
+	}
+	if n := len(s.layers); n > 0 {
+		atomic.AddInt64(&layered, -int64(n))
+		s.layers = nil
+	}
+	s.closeWatchers()
+	s.publish()
+	s.mu.Unlock()
+	return c
+}
+
+// cleared holds what was removed from a store, to be handled once its lock
+// is released.
+type cleared struct {
+	values   valueSet
+	deferred []func()
+}
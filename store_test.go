@@ -0,0 +1,50 @@
+package context
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClearHandlerRecycles(t *testing.T) {
+	r1, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	r2, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+
+	var s *Store
+	h := ClearHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Set(r, key1, "1")
+		s = lookup(r)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), r1)
+	if lookup(r1) != nil || Get(r1, key1) != nil {
+		t.Fatal("Expected ClearHandler to clear the request")
+	}
+	if n := s.values.len(); n != 0 {
+		t.Errorf("Expected the store to be emptied for reuse, got %d values", n)
+	}
+
+	// Simulate the pool handing the store to r2 while r1 still holds a
+	// reference to it: r1 must not see the values of r2.
+	reused := newStoreFor(r2)
+	if reused != s {
+		storePool.Put(reused)
+		reused = s
+		s.mu.Lock()
+		s.owner, s.cleared = r2, false
+		s.mu.Unlock()
+	}
+	reused.values.put(key1, "2")
+	b := currentBackend().(*mapBackend)
+	b.mu.Lock()
+	b.m[r1] = reused
+	b.mu.Unlock()
+	defer b.Release(r1)
+
+	if v, ok := GetOk(r1, key1); ok {
+		t.Errorf("Expected a recycled store to be hidden, got %v", v)
+	}
+	Delete(r1, key1)
+	if v, _ := reused.values.get(key1); v != "2" {
+		t.Error("Expected writes not to reach a recycled store")
+	}
+}
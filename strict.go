@@ -0,0 +1,98 @@
+package context
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// StrictMode configures the detection of requests that are never cleared,
+// usually because the handler was not wrapped with ClearHandler.
+//
+// A request is leaked if it still holds values Grace after its context is
+// done, which the HTTP server does once the handler returns. Requests whose
+// context is never done, such as those created with http.NewRequest, are not
+// checked, nor are requests handed to a connection with TransferToConn.
+type StrictMode struct {
+	// Leaks is the number of leaked requests reported. Once it is reached,
+	// the misuse is logged, or reported by a panic if Panic is set.
+	Leaks int
+	// Panic makes the package panic when a value is stored for a new
+	// request once Leaks requests leaked, so that the stack trace points at
+	// a handler missing ClearHandler.
+	Panic bool
+	// Grace is the delay given to handlers to clear their request after its
+	// context is done. If zero, it is one second. It is waited with the
+	// clock set with SetClock if that is an AfterClock.
+	Grace time.Duration
+}
+
+var (
+	// strict is set while strict mode is enabled, and read whenever a store
+	// is created.
+	strict int64
+	// strictMode is the configuration set with SetStrictMode.
+	strictMode StrictMode
+	// strictLeaks counts the leaked requests.
+	strictLeaks int64
+	// strictPanic holds the message of a pending panic.
+	strictPanic atomic.Pointer[string]
+)
+
+// SetStrictMode enables the detection of leaked requests with the given
+// configuration, or disables it if m is nil. It resets the count of leaked
+// requests.
+//
+// Strict mode watches every request from a goroutine, so it is meant for
+// development and tests rather than production.
+func SetStrictMode(m *StrictMode) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	atomic.StoreInt64(&strictLeaks, 0)
+	strictPanic.Store(nil)
+	if m == nil {
+		atomic.StoreInt64(&strict, 0)
+		return
+	}
+	strictMode = *m
+	if strictMode.Grace <= 0 {
+		strictMode.Grace = time.Second
+	}
+	atomic.StoreInt64(&strict, 1)
+}
+
+// watchLeak checks that r is cleared once its context is done. It is called
+// when a store is created for r.
+func watchLeak(r *http.Request) {
+	if msg := strictPanic.Swap(nil); msg != nil {
+		panic(*msg)
+	}
+	done := r.Context().Done()
+	if done == nil {
+		return
+	}
+	mutex.RLock()
+	m := strictMode
+	mutex.RUnlock()
+	go func() {
+		<-done
+		<-after(m.Grace)
+		// Requests handed to a connection with TransferToConn are cleared
+		// when it is closed.
+		if lookup(r) == nil || isDetached(r) || loadInt(&strict) == 0 {
+			return
+		}
+		if n := atomic.AddInt64(&strictLeaks, 1); n != int64(m.Leaks) {
+			return
+		}
+		msg := fmt.Sprintf("context: %d requests were not cleared, the last one for %s %s: "+
+			"wrap the handler with ClearHandler or call Clear", m.Leaks, r.Method, r.URL)
+		if m.Panic {
+			strictPanic.Store(&msg)
+		} else {
+			log.Print(msg)
+		}
+	}()
+}
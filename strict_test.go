@@ -0,0 +1,137 @@
+package context
+
+import (
+	"bytes"
+	stdcontext "context"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// leak stores a value for a request that is never cleared, and ends its
+// context.
+func leak(t *testing.T) *http.Request {
+	ctx, cancel := stdcontext.WithCancel(stdcontext.Background())
+	r, _ := http.NewRequestWithContext(ctx, "GET", "http://localhost:8080/leak", nil)
+	Set(r, key1, "1")
+	cancel()
+	return r
+}
+
+// logWriter collects log output written from other goroutines.
+type logWriter struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (w *logWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(b)
+}
+
+func (w *logWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+// waitLeaks waits until n leaks were detected.
+func waitLeaks(t *testing.T, n int64) {
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt64(&strictLeaks) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d leaks, got %d", n, atomic.LoadInt64(&strictLeaks))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestStrictMode(t *testing.T) {
+	buf := new(logWriter)
+	log.SetOutput(buf)
+	defer log.SetOutput(log.Writer())
+	SetStrictMode(&StrictMode{Leaks: 2, Grace: 10 * time.Millisecond})
+	defer SetStrictMode(nil)
+
+	// Cleared requests are fine.
+	ctx, cancel := stdcontext.WithCancel(stdcontext.Background())
+	r, _ := http.NewRequestWithContext(ctx, "GET", "http://localhost:8080/", nil)
+	Set(r, key1, "1")
+	Clear(r)
+	cancel()
+
+	defer Clear(leak(t))
+	defer Clear(leak(t))
+	waitLeaks(t, 2)
+	deadline := time.Now().Add(5 * time.Second)
+	for buf.String() == "" && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !strings.Contains(buf.String(), "2 requests were not cleared, the last one for GET http://localhost:8080/leak") {
+		t.Errorf("Expected the leaks to be logged, got %q", buf.String())
+	}
+	if n := atomic.LoadInt64(&strictLeaks); n != 2 {
+		t.Errorf("Expected 2 leaks, got %d", n)
+	}
+}
+
+func TestStrictModePanic(t *testing.T) {
+	SetStrictMode(&StrictMode{Leaks: 1, Panic: true, Grace: 10 * time.Millisecond})
+	defer SetStrictMode(nil)
+	defer Clear(leak(t))
+	waitLeaks(t, 1)
+	for strictPanic.Load() == nil {
+		time.Sleep(time.Millisecond)
+	}
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	defer func() {
+		if p := recover(); p == nil {
+			t.Error("Expected a panic")
+		}
+	}()
+	Set(r, key1, "1")
+}
+
+// graceClock is a Clock whose delays end when the test sends on ch.
+type graceClock struct {
+	manualClock
+	ch chan time.Time
+}
+
+func (c *graceClock) After(d time.Duration) <-chan time.Time {
+	return c.ch
+}
+
+func TestStrictModeDetached(t *testing.T) {
+	c := &graceClock{ch: make(chan time.Time)}
+	SetClock(c)
+	defer SetClock(nil)
+	SetStrictMode(&StrictMode{Leaks: 1, Grace: time.Hour})
+	defer SetStrictMode(nil)
+
+	ctx, cancel := stdcontext.WithCancel(stdcontext.Background())
+	r, _ := http.NewRequestWithContext(ctx, "GET", "http://localhost:8080/ws", nil)
+	Set(r, key1, "1")
+	conn, peer := net.Pipe()
+	defer peer.Close()
+	defer TransferToConn(r, conn).Close()
+	cancel()
+	// The grace delay ends when the clock says so, not after an hour.
+	c.ch <- time.Time{}
+
+	defer Clear(leak(t))
+	c.ch <- time.Time{}
+	waitLeaks(t, 1)
+	time.Sleep(10 * time.Millisecond)
+	if n := atomic.LoadInt64(&strictLeaks); n != 1 {
+		t.Errorf("Expected detached requests not to be reported, got %d leaks", n)
+	}
+}
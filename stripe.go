@@ -0,0 +1,187 @@
+package context
+
+import (
+	"hash/maphash"
+	"net/http"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+var stripeCount int64
+
+// SetStripedLocking makes stores created afterwards split their values into
+// n stripes chosen by a hash of the key, each with its own lock. Goroutines
+// writing to different keys of the same request then do not wait for each
+// other.
+//
+// Writes still lock the whole store while SetMaxEntries or SetMemoryLimit
+// are in effect. Copy-on-write mode takes precedence over striping. If
+// n <= 1, which is the default, values are guarded by a single lock.
+func SetStripedLocking(n int) {
+	atomic.StoreInt64(&stripeCount, int64(n))
+}
+
+type stripe struct {
+	mu     sync.RWMutex
+	values valueSet
+}
+
+var stripeSeed = maphash.MakeSeed()
+
+// initStripes sets up striped mode if it is enabled. It must be called
+// with the store locked for writing, or before it is shared.
+func (s *Store) initStripes() {
+	n := int(loadInt(&stripeCount))
+	if n <= 1 || s.snap.Load() != nil {
+		s.stripes = nil
+	} else if len(s.stripes) != n {
+		s.stripes = make([]stripe, n)
+	}
+}
+
+// stripe returns the stripe of key.
+func (s *Store) stripe(key interface{}) *stripe {
+	return &s.stripes[keyHash(key)%uint64(len(s.stripes))]
+}
+
+// keyHash returns a hash of key consistent with key equality. Keys whose
+// kind can't be hashed cheaply are hashed by type: equal keys always have
+// the same dynamic type.
+func keyHash(key interface{}) uint64 {
+	if key == nil {
+		return 0
+	}
+	switch k := key.(type) {
+	case string:
+		return maphash.String(stripeSeed, k)
+	case int:
+		return mix(uint64(k))
+	}
+	v := reflect.ValueOf(key)
+	switch v.Kind() {
+	case reflect.String:
+		return maphash.String(stripeSeed, v.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return mix(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return mix(v.Uint())
+	case reflect.Ptr, reflect.Chan, reflect.UnsafePointer:
+		return mix(uint64(v.Pointer()))
+	case reflect.Bool:
+		if v.Bool() {
+			return 1
+		}
+		return 0
+	}
+	return maphash.String(stripeSeed, v.Type().String())
+}
+
+func mix(h uint64) uint64 {
+	h *= 0x9e3779b97f4a7c15
+	return h ^ h>>32
+}
+
+// setStriped stores a value holding the store lock shared and the stripe
+// lock exclusively, and reports whether it could. It can't if the store is
+// not in striped mode, doesn't exist yet, or a limit needs the whole store.
+func setStriped(r *http.Request, key, val interface{}) bool {
+	if loadInt(&stripeCount) <= 1 || !plainWrites() {
+		return false
+	}
+	s := lookup(r)
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	ok := s.stripes != nil && !s.cleared && !s.frozen && s.layers == nil && s.watchers == nil && s.ownedBy(r)
+	if ok {
+		st := s.stripe(key)
+		st.mu.Lock()
+		st.values.put(key, val)
+		st.mu.Unlock()
+	}
+	s.mu.RUnlock()
+	return ok
+}
+
+// The following methods access the values of a store in either mode. They
+// must be called with the store locked: for reading by getKey, count,
+// eachRaw and merged, and for writing by putKey and delKey.
+
+func (s *Store) getKey(key interface{}) (interface{}, bool) {
+	v, ok := s.getRawKey(key)
+	return load(v), ok
+}
+
+// getRawKey is getKey without unboxing.
+func (s *Store) getRawKey(key interface{}) (interface{}, bool) {
+	if s.stripes == nil {
+		return s.values.getRaw(key)
+	}
+	st := s.stripe(key)
+	st.mu.RLock()
+	v, ok := st.values.getRaw(key)
+	st.mu.RUnlock()
+	return v, ok
+}
+
+func (s *Store) putKey(key, val interface{}) {
+	if s.stripes == nil {
+		s.values.put(key, val)
+		return
+	}
+	s.stripe(key).values.put(key, val)
+}
+
+func (s *Store) delKey(key interface{}) {
+	if s.stripes == nil {
+		s.values.del(key)
+		return
+	}
+	s.stripe(key).values.del(key)
+}
+
+func (s *Store) count() int {
+	if s.stripes == nil {
+		return s.values.len()
+	}
+	n := 0
+	for i := range s.stripes {
+		st := &s.stripes[i]
+		st.mu.RLock()
+		n += st.values.len()
+		st.mu.RUnlock()
+	}
+	return n
+}
+
+// eachRaw calls f for every entry, without unboxing, until f returns false.
+// f must not modify the store.
+func (s *Store) eachRaw(f func(key, val interface{}) bool) {
+	if s.stripes == nil {
+		s.values.eachRaw(f)
+		return
+	}
+	vs := s.merged()
+	vs.eachRaw(f)
+}
+
+// merged returns a copy of the values of a store in striped mode. All
+// stripes are locked together, so the copy is consistent.
+func (s *Store) merged() valueSet {
+	for i := range s.stripes {
+		s.stripes[i].mu.RLock()
+	}
+	var vs valueSet
+	for i := range s.stripes {
+		s.stripes[i].values.eachRaw(func(k, v interface{}) bool {
+			vs.put(k, v)
+			return true
+		})
+	}
+	for i := range s.stripes {
+		s.stripes[i].mu.RUnlock()
+	}
+	return vs
+}
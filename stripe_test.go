@@ -0,0 +1,65 @@
+package context
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestStripedLocking(t *testing.T) {
+	SetStripedLocking(4)
+	defer SetStripedLocking(0)
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+
+	Set(r, key1, "1")
+	if s := lookup(r); len(s.stripes) != 4 {
+		t.Fatalf("Expected 4 stripes, got %d", len(s.stripes))
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				Set(r, i, j)
+				Get(r, i)
+				GetAll(r)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if n := storeLen(r); n != 9 {
+		t.Errorf("Expected 9 values, got %d", n)
+	}
+	if v := Get(r, 3); v != 99 {
+		t.Errorf("Expected 99, got %v", v)
+	}
+	Delete(r, 3)
+	if _, ok := GetOk(r, 3); ok {
+		t.Error("Expected 3 to be deleted")
+	}
+	if n := len(GetAll(r)); n != 8 {
+		t.Errorf("Expected 8 values, got %d", n)
+	}
+	Clear(r)
+	if Get(r, key1) != nil {
+		t.Error("Expected values to be cleared")
+	}
+}
+
+func TestKeyHash(t *testing.T) {
+	type structKey struct{ a, b int }
+	p := new(int)
+	for _, k := range []interface{}{"a", 1, key1, uint8(2), p, true, structKey{1, 2}, nil} {
+		if keyHash(k) != keyHash(k) {
+			t.Errorf("Expected a stable hash for %v", k)
+		}
+	}
+	if keyHash(structKey{1, 2}) != keyHash(structKey{1, 2}) {
+		t.Error("Expected equal keys to have equal hashes")
+	}
+}
@@ -0,0 +1,77 @@
+package context
+
+import (
+	"net/http"
+)
+
+// SetS stores a value for a given string key in a given request. It is
+// equivalent to Set, but avoids converting the key to interface{}.
+func SetS(r *http.Request, key string, val interface{}) {
+	traceSetS(r, key)
+	observeS(OpSet, r, key, val)
+	s := attach(r)
+	var err error
+	if s.stripes == nil && !s.frozen && s.layers == nil && s.watchers == nil && plainWrites() {
+		s.values.putString(key, val)
+		s.publish()
+	} else {
+		err = s.set(key, val)
+	}
+	s.mu.Unlock()
+	if err == nil {
+		recordSetS(r, key)
+	}
+	_ = checkFrozen(err)
+}
+
+// GetS returns a value stored for a given string key in a given request. It
+// is equivalent to Get, but avoids converting the key to interface{}.
+func GetS(r *http.Request, key string) interface{} {
+	traceGetS(r, key)
+	observeS(OpGet, r, key, nil)
+	v, ok := getS(r, key)
+	for p := r; !ok; {
+		if p = parentFor(p, key); p == nil {
+			break
+		}
+		v, ok = getS(p, key)
+	}
+	if !ok {
+		v, ok = getDefaultS(key)
+	}
+	countGetS(key, ok)
+	return v
+}
+
+func getS(r *http.Request, key string) (interface{}, bool) {
+	s := lookup(r)
+	if s == nil {
+		return nil, false
+	}
+	if snap := s.snap.Load(); snap != nil {
+		if !snap.ownedBy(r) {
+			return nil, false
+		}
+		v, ok := snap.values.getString(key)
+		return load(v), ok
+	}
+	s.mu.RLock()
+	if !s.ownedBy(r) {
+		s.mu.RUnlock()
+		return nil, false
+	}
+	var (
+		v  interface{}
+		ok bool
+	)
+	if s.stripes == nil {
+		v, ok = s.values.getString(key)
+	} else {
+		v, ok = s.getKey(key)
+	}
+	if ok && trackUse() {
+		s.touch(key)
+	}
+	s.mu.RUnlock()
+	return load(v), ok
+}
@@ -0,0 +1,53 @@
+package context
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestStringKeys(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+
+	SetS(r, "a", 1)
+	Set(r, "b", 2)
+	if GetS(r, "b") != 2 || Get(r, "a") != 1 {
+		t.Errorf("Expected string keys to be shared with Set and Get, got %v", GetAll(r))
+	}
+	// A string key never matches a key of another type.
+	type strKey string
+	Set(r, strKey("a"), 3)
+	if GetS(r, "a") != 1 || Get(r, strKey("a")) != 3 {
+		t.Errorf("Unexpected values %v", GetAll(r))
+	}
+
+	for i := 0; i < 2*inlineEntries; i++ {
+		SetS(r, strconv.Itoa(i), i)
+	}
+	if GetS(r, "7") != 7 || GetS(r, "a") != 1 || Get(r, strKey("a")) != 3 {
+		t.Errorf("Unexpected values %v", GetAll(r))
+	}
+	Delete(r, "a")
+	if GetS(r, "a") != nil {
+		t.Error("Expected a to be deleted")
+	}
+	if n := len(GetAll(r)); n != 2*inlineEntries+2 {
+		t.Errorf("Expected %d values, got %d", 2*inlineEntries+2, n)
+	}
+}
+
+func TestStringKeysNoAlloc(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+
+	key := strconv.Itoa(12345)
+	SetS(r, key, true)
+	allocs := testing.AllocsPerRun(100, func() {
+		SetS(r, key, true)
+		GetS(r, key)
+	})
+	if allocs != 0 {
+		t.Errorf("Expected no allocations, got %v", allocs)
+	}
+}
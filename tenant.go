@@ -0,0 +1,64 @@
+package context
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// SetTenant stores the tenant of a request, in multi-tenant applications.
+// The tenant is shown by DebugHandler and Dump, and breaks down the
+// metrics of EnableMetrics.
+func SetTenant(r *http.Request, id string) {
+	Set(r, tenantKey, id)
+}
+
+// Tenant returns the tenant stored with SetTenant, or "".
+func Tenant(r *http.Request) string {
+	id, _ := Get(r, tenantKey).(string)
+	return id
+}
+
+// TenantHandler wraps a handler so that the tenant of every request is set
+// to the one returned by resolve, unless it is "":
+//
+//	h = context.TenantHandler(h, context.TenantFromHeader("X-Tenant"))
+func TenantHandler(h http.Handler, resolve func(r *http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id := resolve(r); id != "" {
+			SetTenant(r, id)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// TenantFromHeader returns a TenantHandler resolver reading the tenant from
+// a request header.
+func TenantFromHeader(name string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// TenantFromHost returns a TenantHandler resolver reading the tenant from
+// the subdomain of domain in the request host: for "example.com", the
+// tenant of "acme.example.com" is "acme". Hosts not under domain have no
+// tenant.
+func TenantFromHost(domain string) func(r *http.Request) string {
+	suffix := "." + strings.ToLower(strings.Trim(domain, "."))
+	return func(r *http.Request) string {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.ToLower(host)
+		if !strings.HasSuffix(host, suffix) {
+			return ""
+		}
+		sub := strings.TrimSuffix(host, suffix)
+		if i := strings.LastIndexByte(sub, '.'); i >= 0 {
+			sub = sub[i+1:]
+		}
+		return sub
+	}
+}
@@ -0,0 +1,78 @@
+package context
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTenant(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	if Tenant(r) != "" {
+		t.Error("Expected no tenant")
+	}
+	SetTenant(r, "acme")
+	if Tenant(r) != "acme" {
+		t.Errorf("Expected acme, got %q", Tenant(r))
+	}
+	if d := Dump(r); !strings.Contains(d, "tenant (context.internalKey) = acme") {
+		t.Errorf("Expected the tenant in the dump, got %q", d)
+	}
+	if stores := debugStores(); len(stores) != 1 || stores[0].Tenant != "acme" {
+		t.Errorf("Expected the tenant in the debug report, got %+v", stores)
+	}
+}
+
+func TestTenantHandler(t *testing.T) {
+	var got string
+	record := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = Tenant(r)
+	})
+	tests := []struct {
+		resolve func(*http.Request) string
+		host    string
+		header  string
+		want    string
+	}{
+		{TenantFromHeader("X-Tenant"), "example.com", "acme", "acme"},
+		{TenantFromHeader("X-Tenant"), "example.com", "", ""},
+		{TenantFromHost("example.com"), "acme.example.com:8080", "", "acme"},
+		{TenantFromHost("example.com"), "www.Acme.example.com", "", "acme"},
+		{TenantFromHost("example.com"), "example.com", "", ""},
+		{TenantFromHost("example.com"), "acme.example.org", "", ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Host = tt.host
+		if tt.header != "" {
+			r.Header.Set("X-Tenant", tt.header)
+		}
+		ClearHandler(TenantHandler(record, tt.resolve)).ServeHTTP(httptest.NewRecorder(), r)
+		if got != tt.want {
+			t.Errorf("%s %s: expected %q, got %q", tt.host, tt.header, tt.want, got)
+		}
+	}
+}
+
+func TestTenantMetrics(t *testing.T) {
+	EnableMetrics(true)
+	defer EnableMetrics(false)
+	m := expvar.Get("gorilla/context").(*expvar.Map)
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	SetTenant(r, "leaky")
+	if v := m.Get("tenants").String(); v != `{"leaky":1}` {
+		t.Errorf("Unexpected live stores by tenant %s", v)
+	}
+	s := lookup(r)
+	s.mu.Lock()
+	s.created = 0
+	s.mu.Unlock()
+	Purge(1)
+	if v := metricsTenantLeaks.Get("leaky"); v == nil || v.String() != "1" {
+		t.Errorf("Expected a leak for the tenant, got %v", v)
+	}
+}
@@ -0,0 +1,54 @@
+package context
+
+import (
+	"net/http"
+	"time"
+)
+
+// StartTime returns the time the first value was stored for a request, or
+// the time TimingHandler or Decorate registered it. It returns the zero time
+// if the request is not registered.
+func StartTime(r *http.Request) time.Time {
+	s := lookup(r)
+	if s == nil {
+		return time.Time{}
+	}
+	s.mu.RLock()
+	created, ok := s.created, s.ownedBy(r) && !s.cleared
+	s.mu.RUnlock()
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(0, created)
+}
+
+// Elapsed returns the time elapsed since StartTime, or 0 if the request is
+// not registered.
+func Elapsed(r *http.Request) time.Duration {
+	start := StartTime(r)
+	if start.IsZero() {
+		return 0
+	}
+	return since(start)
+}
+
+// TimingHandler wraps a handler so that requests are registered as soon as
+// they arrive, for StartTime and Elapsed to measure the whole request. If
+// done is not nil, it is called with the duration of every request when it
+// is cleared, to record latency metrics:
+//
+//	h = context.ClearHandler(context.TimingHandler(h, func(r *http.Request, d time.Duration) {
+//		latency.Observe(d.Seconds())
+//	}))
+func TimingHandler(h http.Handler, done func(r *http.Request, d time.Duration)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Decorate(r)
+		if done != nil {
+			start := StartTime(r)
+			Defer(r, func() {
+				done(r, since(start))
+			})
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,36 @@
+package context
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTiming(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	if !StartTime(r).IsZero() || Elapsed(r) != 0 {
+		t.Error("Expected no start time for an unregistered request")
+	}
+
+	var measured time.Duration
+	var elapsed time.Duration
+	h := TimingHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		elapsed = Elapsed(r)
+	}), func(r *http.Request, d time.Duration) {
+		measured = d
+	})
+	before := time.Now()
+	ClearHandler(h).ServeHTTP(httptest.NewRecorder(), r)
+
+	if elapsed < 10*time.Millisecond || elapsed > time.Since(before) {
+		t.Errorf("Unexpected elapsed time %v", elapsed)
+	}
+	if measured < elapsed {
+		t.Errorf("Expected the request duration to be reported, got %v", measured)
+	}
+	if !StartTime(r).IsZero() {
+		t.Error("Expected no start time once cleared")
+	}
+}
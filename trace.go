@@ -0,0 +1,118 @@
+package context
+
+import (
+	"net/http"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Hooks are functions called on every access to request values, to find out
+// which code reads or writes which keys. Nil functions are skipped.
+//
+// Hooks are called synchronously, possibly with a store locked: they must
+// not use the package.
+type Hooks struct {
+	// OnSet is called by Set, SetE, SetS and Key.Set.
+	OnSet func(r *http.Request, key interface{}, caller string)
+	// OnGet is called by Get, GetOk, GetS and Key.Get.
+	OnGet func(r *http.Request, key interface{}, caller string)
+	// OnDelete is called by Delete and Key.Delete.
+	OnDelete func(r *http.Request, key interface{}, caller string)
+	// OnClear is called by Clear, ClearHandler and Purge.
+	OnClear func(r *http.Request, caller string)
+	// Debug enables caller information: the caller argument is then the
+	// "file:line" of the call outside this package. Otherwise it is empty.
+	// Finding the caller is slow.
+	Debug bool
+}
+
+// hooks holds the Hooks set with SetTraceHooks, if any. It is read on every
+// call, so it is accessed atomically.
+var hooks atomic.Pointer[Hooks]
+
+// SetTraceHooks sets the hooks called on every access to request values.
+// The zero Hooks removes them.
+func SetTraceHooks(h Hooks) {
+	if h.OnSet == nil && h.OnGet == nil && h.OnDelete == nil && h.OnClear == nil {
+		hooks.Store(nil)
+		return
+	}
+	hooks.Store(&h)
+}
+
+// pkgPrefix is the prefix of the names of the functions of this package.
+var pkgPrefix = reflect.TypeOf(Store{}).PkgPath() + "."
+
+// caller returns the location of the first call outside this package. Tests
+// of the package count as outside.
+func caller() string {
+	var pcs [16]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs[:])])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, pkgPrefix) || strings.HasSuffix(f.File, "_test.go") {
+			return f.File + ":" + strconv.Itoa(f.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// The trace functions are called by every function setting, getting or
+// deleting values, whatever the key type: they count the operation for
+// EnableMetrics, then call the hooks.
+
+func traceSet(r *http.Request, key interface{}) {
+	countMetric(&metricsSets, 1)
+	if h := hooks.Load(); h != nil && h.OnSet != nil {
+		h.OnSet(r, key, h.caller())
+	}
+}
+
+func traceGet(r *http.Request, key interface{}) {
+	countMetric(&metricsGets, 1)
+	if h := hooks.Load(); h != nil && h.OnGet != nil {
+		h.OnGet(r, key, h.caller())
+	}
+}
+
+// traceSetS is traceSet for string keys, only converting the key to
+// interface{} if needed.
+func traceSetS(r *http.Request, key string) {
+	countMetric(&metricsSets, 1)
+	if h := hooks.Load(); h != nil && h.OnSet != nil {
+		h.OnSet(r, key, h.caller())
+	}
+}
+
+// traceGetS is traceGet for string keys.
+func traceGetS(r *http.Request, key string) {
+	countMetric(&metricsGets, 1)
+	if h := hooks.Load(); h != nil && h.OnGet != nil {
+		h.OnGet(r, key, h.caller())
+	}
+}
+
+func traceDelete(r *http.Request, key interface{}) {
+	countMetric(&metricsDels, 1)
+	if h := hooks.Load(); h != nil && h.OnDelete != nil {
+		h.OnDelete(r, key, h.caller())
+	}
+}
+
+func traceClear(r *http.Request) {
+	if h := hooks.Load(); h != nil && h.OnClear != nil {
+		h.OnClear(r, h.caller())
+	}
+}
+
+func (h *Hooks) caller() string {
+	if !h.Debug {
+		return ""
+	}
+	return caller()
+}
@@ -0,0 +1,46 @@
+package context
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestTraceHooks(t *testing.T) {
+	var events []string
+	record := func(op string) func(r *http.Request, key interface{}, caller string) {
+		return func(r *http.Request, key interface{}, caller string) {
+			events = append(events, fmt.Sprintf("%s %v %s", op, key, caller))
+		}
+	}
+	SetTraceHooks(Hooks{
+		OnSet:    record("set"),
+		OnGet:    record("get"),
+		OnDelete: record("delete"),
+		OnClear: func(r *http.Request, caller string) {
+			events = append(events, "clear")
+		},
+	})
+	defer SetTraceHooks(Hooks{})
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	Set(r, key1, "1")
+	GetS(r, "a")
+	k := NewKey[int]("k")
+	k.Set(r, 1)
+	k.Delete(r)
+	Clear(r)
+	want := []string{"set 0 ", "get a ", "set k ", "delete k ", "clear"}
+	if fmt.Sprint(events) != fmt.Sprint(want) {
+		t.Errorf("Expected %q, got %q", want, events)
+	}
+
+	events = nil
+	SetTraceHooks(Hooks{OnSet: record("set"), Debug: true})
+	Set(r, key1, "1")
+	defer Clear(r)
+	if len(events) != 1 || !strings.Contains(events[0], "trace_test.go:") {
+		t.Errorf("Expected the caller to be reported, got %q", events)
+	}
+}
@@ -0,0 +1,86 @@
+package context
+
+import (
+	"net/http"
+)
+
+// Transfer copies values stored for src to dst, for handlers building
+// internal sub-requests or replaying requests. If no keys are given, all
+// values are copied, apart from those the package stores for its own
+// bookkeeping. Keys not stored for src itself are skipped: values inherited
+// from a parent or global defaults are not copied.
+//
+// Values are copied as is: maps and slices are shared by both requests, and
+// values set with SetLazy or SetOnce keep their behavior. They are stored
+// like with SetE: it returns the first error from the limits configured for
+// dst, or ErrFrozen, after copying the other values.
+func Transfer(dst, src *http.Request, keys ...interface{}) error {
+	return transfer(dst, src, keys, false)
+}
+
+// Move is Transfer, but also deletes the copied values from src. Values
+// refused by dst stay in src.
+func Move(dst, src *http.Request, keys ...interface{}) error {
+	return transfer(dst, src, keys, true)
+}
+
+// transferred is a value copied by transfer, as stored.
+type transferred struct {
+	key, raw interface{}
+}
+
+func transfer(dst, src *http.Request, keys []interface{}, move bool) error {
+	if dst == src {
+		return nil
+	}
+	vs, s := view(src)
+	if vs == nil {
+		return nil
+	}
+	var values []transferred
+	if len(keys) == 0 {
+		vs.eachRaw(func(k, v interface{}) bool {
+			if _, ok := k.(internalKey); !ok {
+				values = append(values, transferred{k, v})
+			}
+			return true
+		})
+	} else {
+		for _, k := range keys {
+			if v, ok := vs.getRaw(k); ok {
+				values = append(values, transferred{k, v})
+			}
+		}
+	}
+	if s != nil {
+		s.mu.RUnlock()
+	}
+	var first error
+	copied := values[:0]
+	for _, v := range values {
+		if err := SetE(dst, v.key, copyRaw(v.raw)); err != nil {
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+		copied = append(copied, v)
+	}
+	if move {
+		for _, v := range copied {
+			if err := DeleteE(src, v.key); err != nil && first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+// copyRaw returns a stored value to be stored for another request. Boxes
+// updated in place, such as the cells of Key, are copied.
+func copyRaw(v interface{}) interface{} {
+	if c, ok := v.(interface{ copyBox() interface{} }); ok {
+		return c.copyBox()
+	}
+	return v
+}
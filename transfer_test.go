@@ -0,0 +1,91 @@
+package context
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestTransfer(t *testing.T) {
+	src, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	dst, _ := http.NewRequest("GET", "http://localhost:8080/sub", nil)
+	defer Clear(src)
+	defer Clear(dst)
+
+	Set(src, key1, "1")
+	Set(src, key2, "2")
+	if err := Transfer(dst, src, key1, "key3"); err != nil {
+		t.Fatal(err)
+	}
+	if len(GetAll(dst)) != 1 || Get(dst, key1) != "1" {
+		t.Errorf("Expected only key1 to be copied, got %v", GetAll(dst))
+	}
+	if err := Transfer(dst, src); err != nil {
+		t.Fatal(err)
+	}
+	if Get(dst, key2) != "2" || len(GetAll(src)) != 2 {
+		t.Errorf("Expected all values to be copied, got %v %v", GetAll(dst), GetAll(src))
+	}
+
+	if err := Move(dst, src, key2); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := GetOk(src, key2); ok {
+		t.Error("Expected Move to delete the value from src")
+	}
+
+	SetMaxEntries(2)
+	SetEvictionPolicy(RejectNew)
+	defer SetMaxEntries(0)
+	defer SetEvictionPolicy(EvictLRU)
+	Set(src, "key3", "3")
+	if err := Move(dst, src, "key3"); err != ErrMaxEntries {
+		t.Errorf("Expected ErrMaxEntries, got %v", err)
+	}
+	if Get(src, "key3") != "3" {
+		t.Error("Expected refused values to stay in src")
+	}
+}
+
+func TestTransferRaw(t *testing.T) {
+	src, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	dst, _ := http.NewRequest("GET", "http://localhost:8080/sub", nil)
+	defer Clear(src)
+	defer Clear(dst)
+	k := NewKey[int]("count")
+	k.Set(src, 1)
+	SetOnce(src, "redirect", "/login")
+	SetTenant(src, "acme")
+	calls := 0
+	SetLazy(src, "account", func() (interface{}, error) {
+		calls++
+		return "account", nil
+	})
+
+	if err := Transfer(dst, src); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 0 {
+		t.Error("Expected lazy values not to be computed")
+	}
+	if Tenant(dst) != "" {
+		t.Error("Expected internal values not to be copied")
+	}
+	k.Set(dst, 2)
+	if k.Get(src) != 1 || k.Get(dst) != 2 {
+		t.Errorf("Expected typed values to be copied, got %d %d", k.Get(src), k.Get(dst))
+	}
+	if d := Dump(dst); !strings.Contains(d, "[once]") || !strings.Contains(d, "[lazy]") {
+		t.Errorf("Expected the wrappers to be kept, got %q", d)
+	}
+
+	frozen, _ := http.NewRequest("GET", "http://localhost:8080/sub", nil)
+	defer Clear(frozen)
+	Freeze(frozen)
+	if err := Move(frozen, src, k); err != ErrFrozen {
+		t.Errorf("Expected ErrFrozen, got %v", err)
+	}
+	if k.Get(src) != 1 {
+		t.Error("Expected refused values to stay in src")
+	}
+}
@@ -0,0 +1,57 @@
+package context
+
+import (
+	stdcontext "context"
+	"net/http"
+)
+
+// WithRequest returns a copy of ctx from which r can be found with
+// RequestFromContext. Outgoing requests created with it are propagated the
+// values of r by Transport:
+//
+//	out, err := http.NewRequestWithContext(context.WithRequest(r.Context(), r),
+//		"GET", "http://backend/", nil)
+func WithRequest(ctx stdcontext.Context, r *http.Request) stdcontext.Context {
+	return stdcontext.WithValue(ctx, requestKey{}, &requestHolder{r: r})
+}
+
+// Transport returns an http.RoundTripper that sets the headers mapped with
+// SetHeaderMappings to the values stored for the server request that
+// initiated each outgoing request, then sends it with base, or
+// http.DefaultTransport if nil. If no keys are given, all mapped keys are
+// used.
+//
+// The initiating request is found in the context of the outgoing request,
+// with RequestFromContext or Incoming, so outgoing requests must be created
+// with the context of a request that went through LogContext, ProxyHandler,
+// or with WithRequest. Other requests are sent unchanged.
+func Transport(base http.RoundTripper, keys ...string) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base, keys: keys}
+}
+
+type transport struct {
+	base http.RoundTripper
+	keys []string
+}
+
+func (t *transport) RoundTrip(out *http.Request) (*http.Response, error) {
+	in := RequestFromContext(out.Context())
+	if in == nil {
+		in = Incoming(out)
+	}
+	if in == nil {
+		return t.base.RoundTrip(out)
+	}
+	// RoundTrippers must not modify the request.
+	out = out.Clone(out.Context())
+	if err := Inject(in, out.Header, t.keys...); err != nil {
+		if out.Body != nil {
+			out.Body.Close()
+		}
+		return nil, err
+	}
+	return t.base.RoundTrip(out)
+}
@@ -0,0 +1,47 @@
+package context
+
+import (
+	stdcontext "context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTransport(t *testing.T) {
+	SetHeaderMappings(HeaderMapping{Key: "user", Header: "X-User"})
+	defer SetHeaderMappings()
+
+	var got string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("X-User")
+	}))
+	defer backend.Close()
+	client := &http.Client{Transport: Transport(nil)}
+
+	in, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(in)
+	Set(in, "user", "gopher")
+
+	out, _ := http.NewRequestWithContext(WithRequest(stdcontext.Background(), in), "GET", backend.URL, nil)
+	resp, err := client.Do(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got != "gopher" {
+		t.Errorf("Expected the value to be propagated, got %q", got)
+	}
+	if out.Header.Get("X-User") != "" {
+		t.Error("Expected the outgoing request to be left alone")
+	}
+
+	out, _ = http.NewRequest("GET", backend.URL, nil)
+	resp, err = client.Do(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got != "" {
+		t.Errorf("Expected no header without a server request, got %q", got)
+	}
+}
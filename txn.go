@@ -0,0 +1,145 @@
+package context
+
+import (
+	"net/http"
+)
+
+// TxnStore gives access to the values of a request during Txn. Its writes
+// are buffered until the transaction commits.
+type TxnStore interface {
+	// Get returns the value stored for key, as written in the transaction.
+	Get(key interface{}) interface{}
+	// GetOk returns the value stored for key and whether it was present.
+	GetOk(key interface{}) (interface{}, bool)
+	// Set stores a value for key when the transaction commits.
+	Set(key, val interface{})
+	// Delete removes the value stored for key when the transaction commits.
+	Delete(key interface{})
+}
+
+// Txn calls f and applies the writes it made through tx if it returns nil,
+// all at once: concurrent readers see either none or all of them. A
+// middleware storing interdependent values never leaves them half-written:
+//
+//	err := context.Txn(r, func(tx context.TxnStore) error {
+//		tx.Set(sessionKey, session)
+//		user, err := loadUser(session)
+//		if err != nil {
+//			return err
+//		}
+//		tx.Set(userKey, user)
+//		tx.Set(permissionsKey, user.Permissions())
+//		return nil
+//	})
+//
+// If f returns an error, nothing is applied and Txn returns it. If a write
+// is refused, as by SetE, the writes already applied are undone and Txn
+// returns the error. Watchers are only notified of committed writes.
+//
+// The store isn't locked while f runs: f may use the package, and writes
+// made meanwhile by other goroutines are overwritten by those of tx.
+func Txn(r *http.Request, f func(tx TxnStore) error) error {
+	tx := &txn{r: r}
+	if err := f(tx); err != nil {
+		return err
+	}
+	if len(tx.keys) == 0 {
+		return nil
+	}
+	if err := tx.commit(); err != nil {
+		return checkFrozen(err)
+	}
+	for _, k := range tx.keys {
+		c := tx.changes[k]
+		if c.deleted {
+			traceDelete(r, k)
+			recordDelete(r, k)
+			observe(OpDelete, r, k, nil)
+		} else {
+			traceSet(r, k)
+			recordSet(r, k)
+			observe(OpSet, r, k, c.val)
+		}
+	}
+	return nil
+}
+
+// txn implements TxnStore. keys holds the changed keys in order.
+type txn struct {
+	r       *http.Request
+	keys    []interface{}
+	changes map[interface{}]change
+}
+
+func (tx *txn) Get(key interface{}) interface{} {
+	v, _ := tx.GetOk(key)
+	return v
+}
+
+func (tx *txn) GetOk(key interface{}) (interface{}, bool) {
+	if c, ok := tx.changes[key]; ok {
+		return c.val, !c.deleted
+	}
+	return GetOk(tx.r, key)
+}
+
+func (tx *txn) Set(key, val interface{}) {
+	tx.record(key, change{val: val})
+}
+
+func (tx *txn) Delete(key interface{}) {
+	tx.record(key, change{deleted: true})
+}
+
+func (tx *txn) record(key interface{}, c change) {
+	if tx.changes == nil {
+		tx.changes = make(map[interface{}]change)
+	}
+	if _, ok := tx.changes[key]; !ok {
+		tx.keys = append(tx.keys, key)
+	}
+	tx.changes[key] = c
+}
+
+// commit applies the changes under the store lock, publishing them once in
+// copy-on-write mode, and notifying watchers only once all of them are
+// applied.
+func (tx *txn) commit() error {
+	s := attach(tx.r)
+	defer s.mu.Unlock()
+	s.holdPublish = true
+	watchers := s.watchers
+	s.watchers = nil
+	defer func() {
+		s.holdPublish = false
+		s.watchers = watchers
+		s.publish()
+	}()
+	prev := make([]saved, 0, len(tx.keys))
+	for _, k := range tx.keys {
+		v, ok := s.getRawKey(k)
+		prev = append(prev, saved{v, ok})
+		var err error
+		if c := tx.changes[k]; c.deleted {
+			err = s.remove(k)
+		} else {
+			err = s.set(k, c.val)
+		}
+		if err != nil {
+			// Undo the applied changes, last first, without the checks
+			// that may refuse them.
+			for i := len(prev) - 2; i >= 0; i-- {
+				s.restore(tx.keys[i], prev[i])
+			}
+			return err
+		}
+	}
+	if watchers != nil {
+		s.watchers = watchers
+		for _, k := range tx.keys {
+			c := tx.changes[k]
+			s.notifyWatchers(k, unbox(c.val))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package context
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestTxn(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	Set(r, key1, "old")
+	Set(r, "stale", true)
+
+	err := Txn(r, func(tx TxnStore) error {
+		tx.Set(key1, "session")
+		tx.Set(key2, "user")
+		tx.Delete("stale")
+		if tx.Get(key1) != "session" || tx.Get(key2) != "user" {
+			t.Error("Expected the transaction to see its writes")
+		}
+		if _, ok := tx.GetOk("stale"); ok {
+			t.Error("Expected the transaction to see its deletions")
+		}
+		if Get(r, key1) != "old" || Has(r, key2) {
+			t.Error("Expected the writes to be buffered")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Get(r, key1) != "session" || Get(r, key2) != "user" || Has(r, "stale") {
+		t.Errorf("Expected the writes to be applied, got %v", GetAll(r))
+	}
+
+	errAuth := errors.New("auth")
+	err = Txn(r, func(tx TxnStore) error {
+		tx.Set(key1, "other")
+		return errAuth
+	})
+	if err != errAuth || Get(r, key1) != "session" {
+		t.Errorf("Expected nothing applied on error, got %v, %v", err, Get(r, key1))
+	}
+}
+
+func TestTxnRollback(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	Set(r, key1, "old")
+	SetMaxValueSize(32)
+	defer SetMaxValueSize(0)
+
+	err := Txn(r, func(tx TxnStore) error {
+		tx.Set(key1, "new")
+		tx.Set(key2, "new")
+		tx.Set("big", make([]byte, 64))
+		return nil
+	})
+	if err != ErrValueTooLarge {
+		t.Errorf("Expected ErrValueTooLarge, got %v", err)
+	}
+	if Get(r, key1) != "old" || Has(r, key2) || Has(r, "big") {
+		t.Errorf("Expected the applied writes to be undone, got %v", GetAll(r))
+	}
+}
+
+func TestTxnWatch(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	Set(r, key1, "old")
+	ch, cancel := Watch(r, key1)
+	defer cancel()
+
+	SetMaxValueSize(32)
+	err := Txn(r, func(tx TxnStore) error {
+		tx.Set(key1, "rolled back")
+		tx.Set("big", make([]byte, 64))
+		return nil
+	})
+	SetMaxValueSize(0)
+	if err != ErrValueTooLarge {
+		t.Fatalf("Expected ErrValueTooLarge, got %v", err)
+	}
+	select {
+	case v := <-ch:
+		t.Errorf("Expected no notification for a rolled back write, got %v", v)
+	default:
+	}
+
+	err = Txn(r, func(tx TxnStore) error {
+		tx.Set(key1, "new")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case v := <-ch:
+		if v != "new" {
+			t.Errorf("Expected the committed value, got %v", v)
+		}
+	default:
+		t.Error("Expected a notification once committed")
+	}
+}
+
+func TestTxnCopyOnWrite(t *testing.T) {
+	SetCopyOnWrite(true)
+	defer SetCopyOnWrite(false)
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	Set(r, key1, 0)
+	Set(r, key2, 0)
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			all := GetAll(r)
+			if all[key1] != all[key2] {
+				t.Errorf("Expected consistent values, got %v", all)
+				return
+			}
+		}
+	}()
+	for i := 1; i <= 100; i++ {
+		Txn(r, func(tx TxnStore) error {
+			tx.Set(key1, i)
+			tx.Set(key2, i)
+			return nil
+		})
+	}
+	close(done)
+	wg.Wait()
+}
@@ -0,0 +1,129 @@
+package context
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// topUsageKeys is the number of keys listed in HandlerUsage.TopKeys.
+const topUsageKeys = 10
+
+// maxUsageKeys bounds the number of keys counted per handler, like
+// maxKeyStats. Requests storing other keys are counted under otherUsageKey.
+const maxUsageKeys = 1024
+
+// otherUsageKey is the key name the keys past maxUsageKeys are counted
+// under.
+const otherUsageKey = "[other]"
+
+// HandlerUsage describes the values stored by the requests of a handler
+// wrapped with UsageHandler.
+type HandlerUsage struct {
+	// Name is the name given to UsageHandler.
+	Name string
+	// Requests is the number of requests measured.
+	Requests uint64
+	// AvgKeys is the average number of values stored per request.
+	AvgKeys float64
+	// AvgBytes is the average size of the values stored per request, as
+	// measured by MemoryUsage.
+	AvgBytes float64
+	// TopKeys lists the keys stored by the most requests, most used first.
+	TopKeys []KeyUsage
+}
+
+// KeyUsage is the number of requests that stored a key.
+type KeyUsage struct {
+	// Key is the key, as formatted by DebugHandler. Keys past the first
+	// 1024 of a handler are counted together, as "[other]".
+	Key string
+	// Requests is the number of requests that stored it.
+	Requests uint64
+}
+
+// handlerUsage holds the totals of a handler.
+type handlerUsage struct {
+	requests uint64
+	keys     uint64
+	bytes    uint64
+	byKey    map[string]uint64
+}
+
+var (
+	// usageMu guards usage.
+	usageMu sync.Mutex
+	usage   = make(map[string]*handlerUsage)
+)
+
+// UsageHandler wraps a handler so that the values its requests stored are
+// measured when it returns, under name, for UsageReport. Wrapping every
+// route or service with its own name shows which ones use request values as
+// a dumping ground:
+//
+//	router.Handle("/orders", context.UsageHandler(orders, "orders"))
+//
+// It must be wrapped by ClearHandler, or called before the values are
+// cleared otherwise.
+func UsageHandler(h http.Handler, name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer recordUsage(r, name)
+		h.ServeHTTP(w, r)
+	})
+}
+
+func recordUsage(r *http.Request, name string) {
+	keys := Keys(r)
+	bytes := MemoryUsage(r)
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	u := usage[name]
+	if u == nil {
+		u = &handlerUsage{byKey: make(map[string]uint64)}
+		usage[name] = u
+	}
+	u.requests++
+	u.keys += uint64(len(keys))
+	u.bytes += uint64(bytes)
+	for _, k := range keys {
+		name := keyName(k)
+		if _, ok := u.byKey[name]; !ok && len(u.byKey) >= maxUsageKeys {
+			name = otherUsageKey
+		}
+		u.byKey[name]++
+	}
+}
+
+// UsageReport returns the usage of every handler wrapped with UsageHandler
+// since the program started, sorted by name.
+func UsageReport() []HandlerUsage {
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	report := make([]HandlerUsage, 0, len(usage))
+	for name, u := range usage {
+		hu := HandlerUsage{
+			Name:     name,
+			Requests: u.requests,
+			AvgKeys:  float64(u.keys) / float64(u.requests),
+			AvgBytes: float64(u.bytes) / float64(u.requests),
+		}
+		for k, n := range u.byKey {
+			hu.TopKeys = append(hu.TopKeys, KeyUsage{k, n})
+		}
+		sort.Slice(hu.TopKeys, func(i, j int) bool {
+			a, b := hu.TopKeys[i], hu.TopKeys[j]
+			if a.Requests != b.Requests {
+				return a.Requests > b.Requests
+			}
+			return a.Key < b.Key
+		})
+		if len(hu.TopKeys) > topUsageKeys {
+			hu.TopKeys = hu.TopKeys[:topUsageKeys]
+		}
+		report = append(report, hu)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		return report[i].Name < report[j].Name
+	})
+	return report
+}
@@ -0,0 +1,61 @@
+package context
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestUsageReport(t *testing.T) {
+	defer func() {
+		usage = make(map[string]*handlerUsage)
+	}()
+	orders := ClearHandler(UsageHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Set(r, "user", "gopher")
+		if r.URL.Path == "/orders/1" {
+			Set(r, "order", 1)
+		}
+	}), "orders"))
+	health := ClearHandler(UsageHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), "health"))
+
+	orders.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/orders", nil))
+	orders.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/orders/1", nil))
+	health.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+
+	report := UsageReport()
+	if len(report) != 2 || report[0].Name != "health" || report[1].Name != "orders" {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+	if h := report[0]; h.Requests != 1 || h.AvgKeys != 0 || h.TopKeys != nil {
+		t.Errorf("Unexpected health usage %+v", h)
+	}
+	o := report[1]
+	if o.Requests != 2 || o.AvgKeys != 1.5 || o.AvgBytes == 0 {
+		t.Errorf("Unexpected orders usage %+v", o)
+	}
+	want := []KeyUsage{{"user (string)", 2}, {"order (string)", 1}}
+	if !reflect.DeepEqual(o.TopKeys, want) {
+		t.Errorf("Expected %v, got %v", want, o.TopKeys)
+	}
+}
+
+func TestUsageReportKeyLimit(t *testing.T) {
+	defer func() {
+		usage = make(map[string]*handlerUsage)
+	}()
+	h := ClearHandler(UsageHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Set(r, r.URL.Path, 1)
+	}), "ids"))
+	for i := 0; i < maxUsageKeys+10; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/"+strconv.Itoa(i), nil))
+	}
+	u := usage["ids"]
+	if len(u.byKey) != maxUsageKeys+1 || u.byKey[otherUsageKey] != 10 {
+		t.Errorf("Expected %d keys and 10 others, got %d keys and %d others", maxUsageKeys, len(u.byKey), u.byKey[otherUsageKey])
+	}
+	if top := UsageReport()[0].TopKeys; top[0] != (KeyUsage{otherUsageKey, 10}) {
+		t.Errorf("Expected the others to be reported first, got %v", top)
+	}
+}
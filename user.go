@@ -0,0 +1,66 @@
+package context
+
+import (
+	"net/http"
+)
+
+// SetUser stores the authenticated user of a request, whatever its type.
+// Authentication middleware calls it so that handlers and other middleware
+// find the user in one place, with User and IsAuthenticated.
+//
+// Storing nil removes the user.
+func SetUser(r *http.Request, u interface{}) {
+	if u == nil {
+		Delete(r, principalKey)
+		return
+	}
+	Set(r, principalKey, u)
+}
+
+// User returns the user stored with SetUser, or nil.
+func User(r *http.Request) interface{} {
+	return Get(r, principalKey)
+}
+
+// IsAuthenticated reports whether a user is stored for a request.
+func IsAuthenticated(r *http.Request) bool {
+	_, ok := GetOk(r, principalKey)
+	return ok
+}
+
+// UserHandler wraps a handler so that the user of every request is set to
+// the one returned by authenticate, if any. Requests for which it returns an
+// error are answered with 401 Unauthorized.
+//
+//	h = context.UserHandler(h, func(r *http.Request) (interface{}, error) {
+//		user, pass, ok := r.BasicAuth()
+//		if !ok {
+//			return nil, nil // anonymous
+//		}
+//		return users.Check(user, pass)
+//	})
+func UserHandler(h http.Handler, authenticate func(r *http.Request) (interface{}, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, err := authenticate(r)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		if u != nil {
+			SetUser(r, u)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// RequireUser wraps a handler so that requests without a user are answered
+// with 401 Unauthorized.
+func RequireUser(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsAuthenticated(r) {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,49 @@
+package context
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUser(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+
+	if IsAuthenticated(r) || User(r) != nil {
+		t.Error("Expected no user")
+	}
+	SetUser(r, "gopher")
+	if !IsAuthenticated(r) || User(r) != "gopher" {
+		t.Errorf("Expected gopher, got %v", User(r))
+	}
+	SetUser(r, nil)
+	if IsAuthenticated(r) {
+		t.Error("Expected SetUser(nil) to remove the user")
+	}
+}
+
+func TestUserHandler(t *testing.T) {
+	h := ClearHandler(UserHandler(RequireUser(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if User(r) != "gopher" {
+			t.Errorf("Expected gopher, got %v", User(r))
+		}
+	})), func(r *http.Request) (interface{}, error) {
+		switch r.URL.Path {
+		case "/user":
+			return "gopher", nil
+		case "/bad":
+			return nil, errors.New("bad credentials")
+		}
+		return nil, nil
+	}))
+
+	for path, want := range map[string]int{"/user": 200, "/bad": 401, "/anonymous": 401} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != want {
+			t.Errorf("Expected %d for %s, got %d", want, path, w.Code)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package context
+
+// inlineEntries is the number of entries a store holds before it switches
+// to maps. Most requests store only a handful of values, which are faster
+// to scan in place than to hash, and need no allocation.
+const inlineEntries = 8
+
+// valueSet holds the entries of a store: in the inline array while they
+// fit, and in m and strs afterwards.
+//
+// String keys are kept apart from other keys, so that they can be used
+// without converting them to interface{}.
+type valueSet struct {
+	n      int
+	inline [inlineEntries]entry
+	m      map[interface{}]interface{}
+	strs   map[string]interface{}
+}
+
+// entry is an inline entry. String keys are stored in skey and others in
+// key.
+type entry struct {
+	key  interface{}
+	skey string
+	str  bool
+	val  interface{}
+}
+
+// boxed is implemented by stored values that hold the actual value, such
+// as the cells used by Key. Reads return the actual value.
+type boxed interface {
+	unbox() interface{}
+}
+
+func unbox(v interface{}) interface{} {
+	if b, ok := v.(boxed); ok {
+		return b.unbox()
+	}
+	return v
+}
+
+// load is unbox for reads of a single value, which also run the factory of
+// values set with SetLazy.
+func load(v interface{}) interface{} {
+	if l, ok := v.(*lazyValue); ok {
+		v, _ := l.get()
+		return v
+	}
+	return unbox(v)
+}
+
+func (vs *valueSet) get(key interface{}) (interface{}, bool) {
+	v, ok := vs.getRaw(key)
+	return load(v), ok
+}
+
+// getRaw is get without unboxing.
+func (vs *valueSet) getRaw(key interface{}) (interface{}, bool) {
+	if k, ok := key.(string); ok {
+		return vs.getString(k)
+	}
+	if vs.m != nil {
+		v, ok := vs.m[key]
+		return v, ok
+	}
+	for i := 0; i < vs.n; i++ {
+		if e := &vs.inline[i]; !e.str && e.key == key {
+			return e.val, true
+		}
+	}
+	return nil, false
+}
+
+func (vs *valueSet) getString(key string) (interface{}, bool) {
+	if vs.m != nil {
+		v, ok := vs.strs[key]
+		return v, ok
+	}
+	for i := 0; i < vs.n; i++ {
+		if e := &vs.inline[i]; e.str && e.skey == key {
+			return e.val, true
+		}
+	}
+	return nil, false
+}
+
+func (vs *valueSet) put(key, val interface{}) {
+	if k, ok := key.(string); ok {
+		vs.putString(k, val)
+		return
+	}
+	if vs.m != nil {
+		vs.m[key] = val
+		return
+	}
+	for i := 0; i < vs.n; i++ {
+		if e := &vs.inline[i]; !e.str && e.key == key {
+			e.val = val
+			return
+		}
+	}
+	if vs.n < inlineEntries {
+		vs.inline[vs.n] = entry{key: key, val: val}
+		vs.n++
+		return
+	}
+	vs.grow()
+	vs.m[key] = val
+}
+
+func (vs *valueSet) putString(key string, val interface{}) {
+	if vs.m != nil {
+		if vs.strs == nil {
+			vs.strs = make(map[string]interface{})
+		}
+		vs.strs[key] = val
+		return
+	}
+	for i := 0; i < vs.n; i++ {
+		if e := &vs.inline[i]; e.str && e.skey == key {
+			e.val = val
+			return
+		}
+	}
+	if vs.n < inlineEntries {
+		vs.inline[vs.n] = entry{skey: key, str: true, val: val}
+		vs.n++
+		return
+	}
+	vs.grow()
+	if vs.strs == nil {
+		vs.strs = make(map[string]interface{})
+	}
+	vs.strs[key] = val
+}
+
+// grow moves the inline entries to maps.
+func (vs *valueSet) grow() {
+	vs.m = make(map[interface{}]interface{}, 2*inlineEntries)
+	for i := 0; i < vs.n; i++ {
+		if e := &vs.inline[i]; e.str {
+			if vs.strs == nil {
+				vs.strs = make(map[string]interface{}, 2*inlineEntries)
+			}
+			vs.strs[e.skey] = e.val
+		} else {
+			vs.m[e.key] = e.val
+		}
+		vs.inline[i] = entry{}
+	}
+	vs.n = 0
+}
+
+func (vs *valueSet) del(key interface{}) {
+	if k, ok := key.(string); ok {
+		vs.delString(k)
+		return
+	}
+	if vs.m != nil {
+		delete(vs.m, key)
+		return
+	}
+	for i := 0; i < vs.n; i++ {
+		if e := &vs.inline[i]; !e.str && e.key == key {
+			vs.delInline(i)
+			return
+		}
+	}
+}
+
+func (vs *valueSet) delString(key string) {
+	if vs.m != nil {
+		delete(vs.strs, key)
+		return
+	}
+	for i := 0; i < vs.n; i++ {
+		if e := &vs.inline[i]; e.str && e.skey == key {
+			vs.delInline(i)
+			return
+		}
+	}
+}
+
+func (vs *valueSet) delInline(i int) {
+	vs.n--
+	vs.inline[i] = vs.inline[vs.n]
+	vs.inline[vs.n] = entry{}
+}
+
+func (vs *valueSet) len() int {
+	if vs.m != nil {
+		return len(vs.m) + len(vs.strs)
+	}
+	return vs.n
+}
+
+// each calls f for every entry until f returns false. f must not modify the
+// set. Values set with SetLazy and not computed yet are skipped, rather than
+// computed.
+func (vs *valueSet) each(f func(key, val interface{}) bool) {
+	vs.eachRaw(func(k, v interface{}) bool {
+		if pending(v) {
+			return true
+		}
+		return f(k, unbox(v))
+	})
+}
+
+// eachRaw is each without unboxing.
+func (vs *valueSet) eachRaw(f func(key, val interface{}) bool) {
+	if vs.m != nil {
+		for k, v := range vs.strs {
+			if !f(k, v) {
+				return
+			}
+		}
+		for k, v := range vs.m {
+			if !f(k, v) {
+				return
+			}
+		}
+		return
+	}
+	for i := 0; i < vs.n; i++ {
+		e := &vs.inline[i]
+		k := e.key
+		if e.str {
+			k = e.skey
+		}
+		if !f(k, e.val) {
+			return
+		}
+	}
+}
+
+// pending returns the number of values set with SetLazy and not computed
+// yet. It doesn't use eachRaw, so that Len doesn't allocate a closure.
+func (vs *valueSet) pending() int {
+	n := 0
+	if vs.m != nil {
+		for _, v := range vs.strs {
+			if pending(v) {
+				n++
+			}
+		}
+		for _, v := range vs.m {
+			if pending(v) {
+				n++
+			}
+		}
+		return n
+	}
+	for i := 0; i < vs.n; i++ {
+		if pending(vs.inline[i].val) {
+			n++
+		}
+	}
+	return n
+}
+
+// toMap returns a copy of the entries as a map.
+func (vs *valueSet) toMap() map[interface{}]interface{} {
+	m := make(map[interface{}]interface{}, vs.len())
+	vs.each(func(k, v interface{}) bool {
+		m[k] = v
+		return true
+	})
+	return m
+}
+
+// clone returns a copy of the set that shares no maps with it.
+func (vs *valueSet) clone() valueSet {
+	c := *vs
+	if vs.m != nil {
+		c.m = make(map[interface{}]interface{}, len(vs.m))
+		for k, v := range vs.m {
+			c.m[k] = v
+		}
+	}
+	if vs.strs != nil {
+		c.strs = make(map[string]interface{}, len(vs.strs))
+		for k, v := range vs.strs {
+			c.strs[k] = v
+		}
+	}
+	return c
+}
@@ -0,0 +1,40 @@
+package context
+
+import (
+	"testing"
+)
+
+func TestValueSet(t *testing.T) {
+	var vs valueSet
+	for i := 0; i < 2*inlineEntries; i++ {
+		vs.put(i, i)
+		vs.put(i, i*10)
+		if vs.len() != i+1 {
+			t.Fatalf("Expected %d entries, got %d", i+1, vs.len())
+		}
+		for j := 0; j <= i; j++ {
+			if v, ok := vs.get(j); !ok || v != j*10 {
+				t.Fatalf("Expected %d for key %d, got %v", j*10, j, v)
+			}
+		}
+	}
+	if vs.m == nil {
+		t.Error("Expected a map beyond the inline capacity")
+	}
+
+	var small valueSet
+	small.put("a", 1)
+	small.put("b", 2)
+	small.put("c", 3)
+	small.del("a")
+	small.del("missing")
+	if small.len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", small.len())
+	}
+	if _, ok := small.get("a"); ok {
+		t.Error("Expected a to be deleted")
+	}
+	if m := small.toMap(); len(m) != 2 || m["b"] != 2 || m["c"] != 3 {
+		t.Errorf("Unexpected entries %v", m)
+	}
+}
@@ -0,0 +1,87 @@
+package context
+
+import (
+	"net/http"
+)
+
+// watcher is a channel registered with Watch.
+type watcher struct {
+	ch     chan interface{}
+	closed bool
+}
+
+// Watch returns a channel receiving the value stored for a given key in a
+// given request whenever it is set, or nil when it is deleted, for the rest
+// of the request. A logging middleware can finalize its record as soon as the
+// handler stores a summary of the response:
+//
+//	summaries, cancel := context.Watch(r, summaryKey)
+//	defer cancel()
+//	go func() {
+//		for s := range summaries {
+//			log.Finalize(s)
+//		}
+//	}()
+//
+// The channel holds the latest value only: a receiver that falls behind
+// misses intermediate ones. It is closed by cancel and when the request is
+// cleared. Values stored through a Child request are not reported.
+func Watch(r *http.Request, key interface{}) (<-chan interface{}, func()) {
+	w := &watcher{ch: make(chan interface{}, 1)}
+	s := attach(r)
+	if s.watchers == nil {
+		s.watchers = make(map[interface{}][]*watcher)
+	}
+	s.watchers[key] = append(s.watchers[key], w)
+	s.mu.Unlock()
+	cancel := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if w.closed {
+			return
+		}
+		ws := s.watchers[key]
+		for i := range ws {
+			if ws[i] == w {
+				ws = append(ws[:i:i], ws[i+1:]...)
+				break
+			}
+		}
+		if len(ws) == 0 {
+			delete(s.watchers, key)
+		} else {
+			s.watchers[key] = ws
+		}
+		if len(s.watchers) == 0 {
+			s.watchers = nil
+		}
+		w.closed = true
+		close(w.ch)
+	}
+	return w.ch, cancel
+}
+
+// notifyWatchers sends val to the watchers of key. It must be called with
+// the store locked for writing.
+func (s *Store) notifyWatchers(key, val interface{}) {
+	for _, w := range s.watchers[key] {
+		select {
+		case <-w.ch:
+			// Replace the value the receiver didn't get yet.
+		default:
+		}
+		w.ch <- val
+	}
+}
+
+// closeWatchers closes the channels of all watchers. It must be called with
+// the store locked for writing.
+func (s *Store) closeWatchers() {
+	for _, ws := range s.watchers {
+		for _, w := range ws {
+			w.closed = true
+			close(w.ch)
+		}
+	}
+	s.watchers = nil
+}
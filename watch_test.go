@@ -0,0 +1,53 @@
+package context
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestWatch(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	ch, cancel := Watch(r, "summary")
+	other, cancelOther := Watch(r, key1)
+	defer cancelOther()
+
+	SetS(r, "summary", "200 OK")
+	if v := <-ch; v != "200 OK" {
+		t.Errorf("Expected 200 OK, got %v", v)
+	}
+	Set(r, "summary", "first")
+	Set(r, "summary", "latest")
+	if v := <-ch; v != "latest" {
+		t.Errorf("Expected the latest value, got %v", v)
+	}
+	Delete(r, "summary")
+	if v, ok := <-ch; v != nil || !ok {
+		t.Errorf("Expected nil for a deletion, got %v", v)
+	}
+	select {
+	case v := <-other:
+		t.Errorf("Expected no value for other keys, got %v", v)
+	default:
+	}
+
+	cancel()
+	cancel()
+	if _, ok := <-ch; ok {
+		t.Error("Expected cancel to close the channel")
+	}
+	Set(r, "summary", "after")
+
+	k := NewKey[int]("count")
+	counts, _ := Watch(r, k)
+	k.Set(r, 1)
+	k.Set(r, 2)
+	if v := <-counts; v != 2 {
+		t.Errorf("Expected 2, got %v", v)
+	}
+
+	Clear(r)
+	if _, ok := <-other; ok {
+		t.Error("Expected Clear to close the channels")
+	}
+	cancelOther()
+}
@@ -0,0 +1,76 @@
+package context
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+)
+
+// ErrKeyExists is returned by SetOnceStrict, and by SetE for keys registered
+// with WriteOnce, when a value is already stored for the key.
+var ErrKeyExists = errors.New("context: a value is already stored for the key")
+
+var (
+	// writeOnceKeys is set once a key is registered with WriteOnce: it is
+	// read by every Set, so it is accessed atomically.
+	writeOnceKeys int64
+	// writeOnceSet holds the keys registered with WriteOnce, as a
+	// map[interface{}]struct{} replaced on every change.
+	writeOnceSet atomic.Value
+)
+
+// SetOnceStrict stores a value for a given key in a given request, like
+// SetE, unless a value is already stored for the key, in which case it
+// returns ErrKeyExists and keeps the stored value.
+func SetOnceStrict(r *http.Request, key, val interface{}) error {
+	traceSet(r, key)
+	observe(OpSet, r, key, val)
+	s := attach(r)
+	var err error
+	if _, ok := s.getRawKey(key); ok {
+		err = ErrKeyExists
+	} else {
+		err = s.set(key, val)
+	}
+	s.mu.Unlock()
+	if err == nil {
+		recordSet(r, key)
+	}
+	return checkFrozen(err)
+}
+
+// WriteOnce makes keys write-once: once a value is stored for one of them,
+// Set refuses new values and SetE returns ErrKeyExists, so that values such
+// as the authenticated user, the request ID or the trace ID can't be
+// silently replaced by code further down the chain. Values can still be
+// deleted. WriteOnce is meant to be called during program initialization.
+func WriteOnce(keys ...interface{}) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	old, _ := writeOnceSet.Load().(map[interface{}]struct{})
+	m := make(map[interface{}]struct{}, len(old)+len(keys))
+	for k := range old {
+		m[k] = struct{}{}
+	}
+	for _, k := range keys {
+		m[k] = struct{}{}
+	}
+	writeOnceSet.Store(m)
+	atomic.StoreInt64(&writeOnceKeys, 1)
+}
+
+// checkOverwrite refuses to replace the value of a write-once key. It must
+// be called with the store locked.
+func (s *Store) checkOverwrite(key interface{}) error {
+	if loadInt(&writeOnceKeys) == 0 {
+		return nil
+	}
+	m, _ := writeOnceSet.Load().(map[interface{}]struct{})
+	if _, ok := m[key]; !ok {
+		return nil
+	}
+	if _, ok := s.getRawKey(key); ok {
+		return ErrKeyExists
+	}
+	return nil
+}
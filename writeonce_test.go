@@ -0,0 +1,59 @@
+package context
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSetOnceStrict(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	if err := SetOnceStrict(r, key1, "gopher"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetOnceStrict(r, key1, "mallory"); err != ErrKeyExists {
+		t.Errorf("Expected ErrKeyExists, got %v", err)
+	}
+	if Get(r, key1) != "gopher" {
+		t.Errorf("Expected the first value to be kept, got %v", Get(r, key1))
+	}
+	Set(r, key1, "replaced")
+	if Get(r, key1) != "replaced" {
+		t.Error("Expected Set to replace values of other keys")
+	}
+}
+
+func TestWriteOnce(t *testing.T) {
+	k := NewKey[string]("request id")
+	WriteOnce("user", k)
+	defer func() {
+		writeOnceSet.Store(map[interface{}]struct{}(nil))
+		atomic.StoreInt64(&writeOnceKeys, 0)
+	}()
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	defer Clear(r)
+	SetS(r, "user", "gopher")
+	SetS(r, "user", "mallory")
+	if err := SetE(r, "user", "mallory"); err != ErrKeyExists {
+		t.Errorf("Expected ErrKeyExists, got %v", err)
+	}
+	if GetS(r, "user") != "gopher" {
+		t.Errorf("Expected the first value to be kept, got %v", GetS(r, "user"))
+	}
+	k.Set(r, "1")
+	if err := k.SetE(r, "2"); err != ErrKeyExists || k.Get(r) != "1" {
+		t.Errorf("Expected typed keys to be write-once, got %v, %v", err, k.Get(r))
+	}
+	Set(r, key1, "1")
+	Set(r, key1, "2")
+	if Get(r, key1) != "2" {
+		t.Error("Expected other keys to be writable")
+	}
+
+	Delete(r, "user")
+	if err := SetE(r, "user", "alice"); err != nil {
+		t.Errorf("Expected a deleted value to be settable again, got %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package context
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// contextWriter is the response writer installed by ClearHandler, holding
+// the values set with SetW.
+type contextWriter struct {
+	http.ResponseWriter
+	mu     sync.Mutex
+	values map[interface{}]interface{}
+}
+
+// newContextWriter wraps w in a contextWriter that is an http.Flusher,
+// http.Hijacker or http.Pusher only if w is, so that type assertions on the
+// writer keep telling the truth. Other optional interfaces are found
+// through Unwrap, as by http.ResponseController.
+//
+// Writers are not recycled: handlers may keep them past ClearHandler, as
+// for detached requests.
+func newContextWriter(w http.ResponseWriter) http.ResponseWriter {
+	cw := &contextWriter{ResponseWriter: w}
+	f, isFlusher := w.(http.Flusher)
+	h, isHijacker := w.(http.Hijacker)
+	p, isPusher := w.(http.Pusher)
+	switch {
+	case isFlusher && isHijacker && isPusher:
+		return struct {
+			*contextWriter
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+		}{cw, f, h, p}
+	case isFlusher && isHijacker:
+		return struct {
+			*contextWriter
+			http.Flusher
+			http.Hijacker
+		}{cw, f, h}
+	case isFlusher && isPusher:
+		return struct {
+			*contextWriter
+			http.Flusher
+			http.Pusher
+		}{cw, f, p}
+	case isHijacker && isPusher:
+		return struct {
+			*contextWriter
+			http.Hijacker
+			http.Pusher
+		}{cw, h, p}
+	case isFlusher:
+		return struct {
+			*contextWriter
+			http.Flusher
+		}{cw, f}
+	case isHijacker:
+		return struct {
+			*contextWriter
+			http.Hijacker
+		}{cw, h}
+	case isPusher:
+		return struct {
+			*contextWriter
+			http.Pusher
+		}{cw, p}
+	}
+	return cw
+}
+
+// self returns w, also for the writers of newContextWriter embedding it.
+func (w *contextWriter) self() *contextWriter {
+	return w
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController.
+func (w *contextWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// ReadFrom lets the wrapped writer read src if it is an io.ReaderFrom, so
+// that io.Copy keeps using sendfile for files, and copies src otherwise.
+func (w *contextWriter) ReadFrom(src io.Reader) (int64, error) {
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		return rf.ReadFrom(src)
+	}
+	// Hide the ReadFrom method of w from io.Copy.
+	return io.Copy(struct{ io.Writer }{w.ResponseWriter}, src)
+}
+
+// findWriter returns the writer installed by ClearHandler that w is or
+// wraps, or nil.
+func findWriter(w http.ResponseWriter) *contextWriter {
+	for w != nil {
+		switch t := w.(type) {
+		case interface{ self() *contextWriter }:
+			return t.self()
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = t.Unwrap()
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// SetW stores a value for a given key in the response writer of a request,
+// for data discovered while writing the response, such as the status code,
+// that middleware further up in the chain reads once the handler returned.
+//
+// The values are held by the writer installed by ClearHandler, which must be
+// w or be wrapped by it, and that provides Unwrap methods. SetW does nothing
+// for other writers.
+func SetW(w http.ResponseWriter, key, val interface{}) {
+	cw := findWriter(w)
+	if cw == nil {
+		return
+	}
+	cw.mu.Lock()
+	if cw.values == nil {
+		cw.values = make(map[interface{}]interface{})
+	}
+	cw.values[key] = val
+	cw.mu.Unlock()
+}
+
+// GetW returns a value stored with SetW for a given key in a response
+// writer.
+func GetW(w http.ResponseWriter, key interface{}) interface{} {
+	value, _ := GetWOk(w, key)
+	return value
+}
+
+// GetWOk returns a value stored with SetW and whether it was found.
+func GetWOk(w http.ResponseWriter, key interface{}) (interface{}, bool) {
+	cw := findWriter(w)
+	if cw == nil {
+		return nil, false
+	}
+	cw.mu.Lock()
+	value, ok := cw.values[key]
+	cw.mu.Unlock()
+	return value, ok
+}
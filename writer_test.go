@@ -0,0 +1,90 @@
+package context
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// statusWriter is a middleware writer recording the status code with SetW.
+type statusWriter struct {
+	http.ResponseWriter
+}
+
+func (w statusWriter) WriteHeader(code int) {
+	SetW(w, "status", code)
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func TestSetW(t *testing.T) {
+	var status interface{}
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	outer := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inner.ServeHTTP(statusWriter{w}, r)
+		status = GetW(w, "status")
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("Expected the writer to be an http.Flusher")
+		}
+	})
+	rec := httptest.NewRecorder()
+	ClearHandler(outer).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if status != http.StatusTeapot || rec.Code != http.StatusTeapot {
+		t.Errorf("Expected status %d, got %v", http.StatusTeapot, status)
+	}
+
+	// Writers not installed by ClearHandler hold nothing.
+	SetW(rec, "status", 200)
+	if _, ok := GetWOk(rec, "status"); ok {
+		t.Error("Expected no value without ClearHandler")
+	}
+}
+
+// readerFromWriter is a writer that records the use of ReadFrom.
+type readerFromWriter struct {
+	*httptest.ResponseRecorder
+	readFrom bool
+}
+
+func (w *readerFromWriter) ReadFrom(src io.Reader) (int64, error) {
+	w.readFrom = true
+	return io.Copy(w.ResponseRecorder, src)
+}
+
+func TestClearHandlerWriter(t *testing.T) {
+	var kept http.ResponseWriter
+	rec := &readerFromWriter{ResponseRecorder: httptest.NewRecorder()}
+	ClearHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("Expected the writer of a recorder to be an http.Flusher")
+		}
+		if _, ok := w.(http.Hijacker); ok {
+			t.Error("Expected the writer of a recorder not to be an http.Hijacker")
+		}
+		if _, ok := w.(http.Pusher); ok {
+			t.Error("Expected the writer of a recorder not to be an http.Pusher")
+		}
+		if _, err := io.Copy(w, io.LimitReader(strings.NewReader("body"), 10)); err != nil {
+			t.Fatal(err)
+		}
+		SetW(w, "status", 200)
+		kept = w
+	})).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if !rec.readFrom || rec.Body.String() != "body" {
+		t.Errorf("Expected io.Copy to use the ReadFrom of the wrapped writer, got %v %q", rec.readFrom, rec.Body)
+	}
+	// Writers kept by handlers are not recycled for other requests.
+	ClearHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetW(w, "status", 500)
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if v := GetW(kept, "status"); v != 200 {
+		t.Errorf("Expected the kept writer to hold its own values, got %v", v)
+	}
+}